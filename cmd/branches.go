@@ -0,0 +1,121 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/ttauveron/git-follow-up/git"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// branchesCmd represents the branches command
+var branchesCmd = &cobra.Command{
+	Use:   "branches",
+	Short: "Lists branches of your tracked repositories",
+	Long: `Lists branches of your tracked repositories, with their last commit, and ahead/behind counts against the default branch
+Useful for cleaning up forgotten feature branches.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		labels, _ := cmd.Flags().GetStringSlice("label")
+		unmerged, _ := cmd.Flags().GetBool("unmerged")
+
+		var staleBefore time.Time
+		if cmd.Flags().Changed("stale") {
+			stale, _ := cmd.Flags().GetString("stale")
+			age, err := git.ParseAge(stale)
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				return
+			}
+			staleBefore = time.Now().Add(-age)
+		}
+
+		// Sync repos if update flag is provided
+		doUpdate, err := cmd.Flags().GetBool("update")
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
+
+		if doUpdate {
+			updateCmd.Run(cmd, args)
+		}
+
+		var branches []git.Branch
+		for _, repo := range config.Repositories {
+			// Skip repos with non-matching labels
+			if !git.ContainsAll(repo.Labels, labels) {
+				continue
+			}
+			bs, err := repo.ListBranches()
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				continue
+			}
+			for _, branch := range bs {
+				if unmerged && branch.Merged {
+					continue
+				}
+				if !staleBefore.IsZero() && !branch.LastCommit.Committer.When.Before(staleBefore) {
+					continue
+				}
+				branches = append(branches, branch)
+			}
+		}
+
+		sort.Sort(git.ByLastCommit(branches))
+
+		// initialize tabwriter
+		w := new(tabwriter.Writer)
+		defer w.Flush()
+
+		// minwidth, tabwidth, padding, padchar, flags
+		w.Init(os.Stdout, 8, 8, 0, ' ', 0)
+		for _, branch := range branches {
+			fmt.Fprintln(w, formatBranch(branch))
+		}
+	},
+}
+
+func formatBranch(b git.Branch) (result string) {
+	status := fmt.Sprintf("+%d -%d", b.Ahead, b.Behind)
+	switch {
+	case b.Default:
+		status = "default"
+	case b.Merged:
+		status += " merged"
+	default:
+		status += " unmerged"
+	}
+
+	result += "\033[1;31m" + b.Repository + "\t \033[0m"
+	result += "\033[1;33m" + b.Name + "\t \033[0m"
+	result += "\033[1;36m" + b.LastCommit.Committer.When.Format("2006-01-02 15:04") + "\t \033[0m"
+	result += status + " \t"
+	result += "\033[1;32m" + b.LastCommit.Author.Name + "\033[0m"
+	return result
+}
+
+func init() {
+	branchesCmd.Flags().StringSlice("label", []string{}, "filters by project labels")
+	branchesCmd.Flags().String("stale", "", "only lists branches without commits for the given age (e.g. 30d, 2w, 6m)")
+	branchesCmd.Flags().Bool("unmerged", false, "only lists branches not merged into the default branch")
+	branchesCmd.Flags().BoolP("update", "u", false, "synchronizes git repositories")
+	rootCmd.AddCommand(branchesCmd)
+}
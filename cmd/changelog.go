@@ -0,0 +1,142 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/ttauveron/git-follow-up/git"
+	"sort"
+	"strings"
+)
+
+var changelogGroupByArgs = []string{"none", "repo", "label"}
+
+// changelogCmd represents the changelog command
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Generates a markdown changelog from Conventional Commits",
+	Long: `Generates a markdown changelog from commit subjects following the Conventional Commits spec (type(scope)!: description)
+Commits are grouped into breaking changes, features, fixes and other changes.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		filter, err = git.NewFilter(cmd.Flags())
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+
+		// Sync repos if update flag is provided
+		doUpdate, err := cmd.Flags().GetBool("update")
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
+
+		if doUpdate {
+			updateCmd.Run(cmd, args)
+		}
+
+		groupBy, _ := cmd.Flags().GetString("group-by")
+		withAuthors, _ := cmd.Flags().GetBool("authors")
+		withHashes, _ := cmd.Flags().GetBool("hashes")
+		sinceTag, _ := cmd.Flags().GetString("since-tag")
+
+		repos := filterRepos(*filter)
+
+		var changelogs []*git.Changelog
+		switch groupBy {
+		case "repo":
+			for _, repo := range repos {
+				commits := changelogCommits([]git.Repository{repo}, *filter, sinceTag)
+				if len(commits) > 0 {
+					changelogs = append(changelogs, git.NewChangelog(repo.Name, commits))
+				}
+			}
+			break
+		case "label":
+			reposByLabel := make(map[string][]git.Repository)
+			var labels []string
+			for _, repo := range repos {
+				for _, label := range repo.Labels {
+					if _, ok := reposByLabel[label]; !ok {
+						labels = append(labels, label)
+					}
+					reposByLabel[label] = append(reposByLabel[label], repo)
+				}
+			}
+			sort.Strings(labels)
+			for _, label := range labels {
+				commits := changelogCommits(reposByLabel[label], *filter, sinceTag)
+				if len(commits) > 0 {
+					changelogs = append(changelogs, git.NewChangelog(label, commits))
+				}
+			}
+			break
+		case "none":
+			title := "Changelog"
+			if len(filter.Labels) > 0 {
+				title += " (" + strings.Join(filter.Labels, ", ") + ")"
+			}
+			changelogs = append(changelogs, git.NewChangelog(title, changelogCommits(repos, *filter, sinceTag)))
+			break
+		default:
+			fmt.Printf("group-by flag not recognized, possible values : %s\n", strings.Join(changelogGroupByArgs, ", "))
+			return
+		}
+
+		for i, changelog := range changelogs {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Print(changelog.Markdown(withAuthors, withHashes))
+		}
+	},
+}
+
+// changelogCommits lists the commits of the repositories, since the commit of the tag in each of them if any
+func changelogCommits(repos []git.Repository, f git.Filter, sinceTag string) []git.Commit {
+	if sinceTag == "" {
+		return listCommits(repos, f)
+	}
+	var commits []git.Commit
+	for _, repo := range repos {
+		tag, err := repo.FindTag(sinceTag)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			continue
+		}
+		repoFilter := f
+		repoFilter.From = tag.Commit.Author.When
+		for _, c := range listCommits([]git.Repository{repo}, repoFilter) {
+			if c.Commit.Hash != tag.Commit.Hash {
+				commits = append(commits, c)
+			}
+		}
+	}
+	sort.Sort(git.ByDate(commits))
+	return commits
+}
+
+func init() {
+	addFilterFlags(changelogCmd.Flags())
+
+	changelogCmd.Flags().String("group-by", "none", "groups the changelog ("+strings.Join(changelogGroupByArgs, ", ")+")")
+	changelogCmd.Flags().String("since-tag", "", "lists the commits since the given tag of each repo, instead of the from flag")
+	changelogCmd.Flags().Bool("authors", false, "includes commit authors")
+	changelogCmd.Flags().Bool("hashes", false, "includes short commit hashes")
+	rootCmd.AddCommand(changelogCmd)
+}
@@ -18,6 +18,7 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/ttauveron/git-follow-up/git"
 	"os"
 	"sort"
@@ -32,7 +33,18 @@ var commitsCmd = &cobra.Command{
 	Use:   "commits",
 	Short: "Get list of commits from your tracked repositories",
 	Run: func(cmd *cobra.Command, args []string) {
-		filter = git.NewFilter(cmd.Flags())
+		var err error
+		filter, err = git.NewFilter(cmd.Flags())
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		if !git.Contains(git.OutputArgs, output) {
+			fmt.Printf("output flag not recognized, possible values : %s\n", strings.Join(git.OutputArgs, ", "))
+			return
+		}
 
 		// Sync repos if update flag is provided
 		doUpdate, err := cmd.Flags().GetBool("update")
@@ -44,22 +56,31 @@ var commitsCmd = &cobra.Command{
 			updateCmd.Run(cmd, args)
 		}
 
-		var commits []git.Commit
+		commits := listCommits(filterRepos(*filter), *filter)
 
-		// Listing log messages of repositories
-		for _, repo := range config.Repositories {
-			// Skip update on non-matching labels
-			if cmd.Flags().Changed("label") && !git.ContainsAll(repo.Labels, filter.Labels) {
-				continue
+		// Collapse duplicates if dedup flag is provided
+		if cmd.Flags().Changed("dedup") {
+			dedup, _ := cmd.Flags().GetString("dedup")
+			if !git.Contains(git.DedupArgs, dedup) {
+				fmt.Printf("dedup flag not recognized, possible values : %s\n", strings.Join(git.DedupArgs, ", "))
+				return
 			}
-			cs, err := repo.ListCommits(*filter)
-			commits = append(commits, cs...)
+			commits, err = git.Dedup(commits, dedup == "patch-id")
 			if err != nil {
 				fmt.Printf("%v\n", err)
+				return
 			}
 		}
 
-		sort.Sort(git.ByDate(commits))
+		if output == "atom" {
+			content, err := git.NewFeed(*filter, "", commits).Xml()
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				return
+			}
+			fmt.Print(string(content))
+			return
+		}
 
 		// initialize tabwriter
 		w := new(tabwriter.Writer)
@@ -68,64 +89,78 @@ var commitsCmd = &cobra.Command{
 		// minwidth, tabwidth, padding, padchar, flags
 		w.Init(os.Stdout, 8, 8, 0, ' ', 0)
 		for _, commit := range commits {
-			fmt.Fprintf(w, formatCommit(commit)+"\n")
+			fmt.Fprintln(w, formatCommit(commit))
 		}
 
 	},
 }
 
-func formatCommit(c git.Commit) (result string) {
-	message := strings.Split(c.Commit.Message, "\n")[0]
-	if len(message) > 70 {
-		message = message[:70] + "..."
-	}
-	hash := c.Commit.Hash.String()[:8]
-	author := c.Commit.Author.Name
-	date := c.Commit.Author.When.Format("2006-01-02 15:04")
-	name := c.Name
-
-	// Color reference : https://stackoverflow.com/questions/5947742/how-to-change-the-output-color-of-echo-in-linux
-	if git.Contains(filter.Display, "repo") {
-		result += "\033[1;31m" + name + "\t \033[0m"
-	}
-
-	if git.Contains(filter.Display, "date") {
-		result += "\033[1;36m" + date + "\t \033[0m"
-	}
-
-	if git.Contains(filter.Display, "hash") {
-		result += "\033[1;34m" + hash + "\t\033[0m"
-	}
-
-	if git.Contains(filter.Display, "message") {
-		result += " " + message + " \t"
+// filterRepos returns the configured repositories matching the label filter
+func filterRepos(f git.Filter) (repos []git.Repository) {
+	for _, repo := range config.Repositories {
+		// Skip repos with non-matching labels
+		if !git.ContainsAll(repo.Labels, f.Labels) {
+			continue
+		}
+		repos = append(repos, repo)
 	}
+	return repos
+}
 
-	if git.Contains(filter.Display, "author") {
-		result += "\033[1;32m" + author + "\033[0m"
+// listCommits lists log messages of repositories, sorted by date
+func listCommits(repos []git.Repository, f git.Filter) (commits []git.Commit) {
+	for _, repo := range repos {
+		cs, err := repo.ListCommits(f)
+		commits = append(commits, cs...)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
 	}
-
-	return result
+	sort.Sort(git.ByDate(commits))
+	return commits
 }
 
-func init() {
+func formatCommit(c git.Commit) string {
+	return c.Format(filter.Display)
+}
 
-	commitsCmd.Flags().StringSlice("label", []string{}, "filters by project labels")
-	commitsCmd.Flags().StringSlice("author", []string{}, "filters by authors")
+// addFilterFlags registers the flags read by git.NewFilter
+func addFilterFlags(flags *pflag.FlagSet) {
+	flags.StringSlice("label", []string{}, "filters by project labels")
+	flags.StringSlice("author", []string{}, "filters by authors")
+	flags.Bool("co-authors", false, "author filter also matches Co-authored-by trailers")
+	flags.StringSlice("trailer", []string{}, "filters by commit trailers (key=value, e.g. Reviewed-by=jean)")
+	flags.StringSlice("issue", []string{}, "filters by referenced issues (PROJ-123, #456)")
 
-	commitsCmd.Flags().String("from", "wtd", "filters commit by date (ytd, mtd, wtd, yesterday, today, [yyyy-MM-dd])")
+	flags.String("from", "wtd", "filters commit by date (ytd, mtd, wtd, yesterday, today, [yyyy-MM-dd])")
 	annotation := make(map[string][]string)
 	annotation[cobra.BashCompCustom] = []string{"__from_values"}
-	flag := commitsCmd.Flags().Lookup("from")
+	flag := flags.Lookup("from")
 	flag.Annotations = annotation
 
+	flags.String("to", "", "filters commit by date, the given day being included (yesterday, today, [yyyy-MM-dd])")
 	annotation = make(map[string][]string)
+	annotation[cobra.BashCompCustom] = []string{"__to_values"}
+	flag = flags.Lookup("to")
+	flag.Annotations = annotation
+
+	flags.BoolP("update", "u", false, "synchronizes git repositories")
+}
+
+func init() {
+	addFilterFlags(commitsCmd.Flags())
+
+	annotation := make(map[string][]string)
 	annotation[cobra.BashCompCustom] = []string{"__display_values"}
 	commitsCmd.Flags().StringSlice("display", []string{}, "fields to be displayed")
-	flag = commitsCmd.Flags().Lookup("display")
+	flag := commitsCmd.Flags().Lookup("display")
 	flag.Annotations = annotation
 
-	commitsCmd.Flags().BoolP("update", "u", false, "synchronizes git repositories")
+	commitsCmd.Flags().String("dedup", "", "collapses duplicate commits found in several repos or branches, by hash or patch-id")
+	commitsCmd.Flags().Lookup("dedup").NoOptDefVal = "hash"
+
+	commitsCmd.Flags().String("output", "text", "output format ("+strings.Join(git.OutputArgs, ", ")+")")
+
 	rootCmd.AddCommand(commitsCmd)
 
 }
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"strings"
+	"testing"
+)
+
+func TestBashCompletion(t *testing.T) {
+	var out bytes.Buffer
+	if err := rootCmd.GenBashCompletion(&out); err != nil {
+		t.Fatal(err)
+	}
+	script := out.String()
+
+	// The completion functions of the commits flags
+	for _, function := range []string{"__display_values()", "__from_values()", "__to_values()"} {
+		if !strings.Contains(script, function) {
+			t.Errorf("completion script has no %v function", function)
+		}
+	}
+	for _, want := range []string{
+		`flags_completion+=("__from_values")`,
+		`flags_completion+=("__to_values")`,
+		`compgen -W "yesterday today"`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("completion script has no %v", want)
+		}
+	}
+
+	for _, name := range []string{"from", "to"} {
+		flag := commitsCmd.Flags().Lookup(name)
+		if got := flag.Annotations[cobra.BashCompCustom]; len(got) != 1 || got[0] != "__"+name+"_values" {
+			t.Errorf("completion of --%v = %v, want __%v_values", name, got, name)
+		}
+	}
+}
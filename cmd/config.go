@@ -0,0 +1,104 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed config.schema.json
+var configSchema string
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Validates the config file",
+	// The config file is validated by the subcommands
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Checks the config file, reporting errors with their line number",
+	Run: func(cmd *cobra.Command, args []string) {
+		files := configFiles
+		if len(files) == 0 {
+			files = []string{configFileUsed()}
+		}
+		errors, err := validateConfigFiles(files)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		for _, err := range errors {
+			fmt.Printf("%v\n", err)
+		}
+		if len(errors) > 0 {
+			os.Exit(1)
+		}
+		var validated []string
+		for _, file := range files {
+			if isYamlFile(file) {
+				validated = append(validated, file)
+			} else {
+				fmt.Printf("%s skipped, only yaml config files are validated\n", file)
+			}
+		}
+		if len(validated) > 0 {
+			fmt.Printf("%s valid\n", strings.Join(validated, ", "))
+		}
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Prints the JSON Schema of the config file",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(configSchema)
+	},
+}
+
+// validateConfigFiles checks config files, repository names having to be unique across all of them
+// Only yaml files are checked, viper also reading json, toml and other formats
+func validateConfigFiles(paths []string) ([]error, error) {
+	v := newConfigValidator()
+	for _, path := range paths {
+		if !isYamlFile(path) {
+			continue
+		}
+		doc, err := loadConfigDocument(path)
+		if err != nil {
+			return nil, err
+		}
+		v.validate(doc)
+	}
+	return v.errors, nil
+}
+
+func isYamlFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd, configSchemaCmd)
+	rootCmd.AddCommand(configCmd)
+}
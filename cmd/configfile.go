@@ -0,0 +1,253 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/ttauveron/git-follow-up/git"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// repositoryEntry is the yaml representation of a repository in the config file
+type repositoryEntry struct {
+	Name           string               `yaml:"name"`
+	Url            string               `yaml:"url,omitempty"`
+	Path           string               `yaml:"path,omitempty"`
+	Authentication *authenticationEntry `yaml:"authentication,omitempty"`
+	Labels         []string             `yaml:"labels,omitempty"`
+}
+
+type authenticationEntry struct {
+	Type     string `yaml:"type"`
+	AuthFile string `yaml:"auth_file,omitempty"`
+	Token    string `yaml:"token,omitempty"`
+	Username string `yaml:"username,omitempty"`
+}
+
+func newRepositoryEntry(r git.Repository) repositoryEntry {
+	entry := repositoryEntry{
+		Name:   r.Name,
+		Url:    r.Url,
+		Path:   r.Path,
+		Labels: r.Labels,
+	}
+	if r.Authentication.Type != "" {
+		entry.Authentication = &authenticationEntry{
+			Type:     r.Authentication.Type,
+			AuthFile: r.Authentication.AuthFile,
+			Token:    r.Authentication.Token,
+			Username: r.Authentication.Username,
+		}
+	}
+	return entry
+}
+
+// newEntries skips repositories already in the config, and makes names unique
+func newEntries(repos []git.Repository) (entries []repositoryEntry) {
+	names := make(map[string]bool)
+	known := make(map[string]bool)
+	for _, repo := range config.Repositories {
+		names[repo.Name] = true
+		if repo.Url != "" {
+			known[git.NormalizeUrl(repo.Url)] = true
+		}
+		known[repo.LocalPath] = true
+	}
+
+	for _, repo := range repos {
+		if (repo.Url != "" && known[git.NormalizeUrl(repo.Url)]) || (repo.Path != "" && known[repo.Path]) {
+			continue
+		}
+		name := repo.Name
+		for i := 2; names[name]; i++ {
+			name = repo.Name + "-" + strconv.Itoa(i)
+		}
+		names[name] = true
+		repo.Name = name
+		entries = append(entries, newRepositoryEntry(repo))
+	}
+	return entries
+}
+
+// addEntries prints the entries, and appends them to the config file after confirmation
+func addEntries(entries []repositoryEntry, dryRun bool, yes bool) {
+	out, err := yaml.Marshal(map[string][]repositoryEntry{"repositories": entries})
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	fmt.Print(string(out))
+
+	if dryRun {
+		return
+	}
+
+	file := configFileUsed()
+	if !yes && !confirm(fmt.Sprintf("Append %d repositories to %s?", len(entries), file)) {
+		return
+	}
+
+	doc, err := loadConfigDocument(file)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	for _, entry := range entries {
+		if err := doc.addRepository(entry); err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+	}
+	if err := doc.save(); err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	fmt.Printf("%d repositories added to %s\n", len(entries), file)
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// configFileUsed returns the path of the main config file, edited by commands, even if it doesn't exist yet
+func configFileUsed() string {
+	if len(configFiles) > 0 {
+		return configFiles[0]
+	}
+	if len(cfgFiles) > 0 {
+		return cfgFiles[0]
+	}
+	return configPath + "/config.yaml"
+}
+
+// configDocument is a config file parsed as a yaml node tree,
+// so that it can be edited while preserving comments and ordering
+type configDocument struct {
+	path string
+	root yaml.Node
+}
+
+func loadConfigDocument(path string) (*configDocument, error) {
+	doc := &configDocument{path: path}
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, &doc.root); err != nil {
+		return nil, fmt.Errorf("%v : %v", path, err)
+	}
+	if doc.root.Kind == 0 {
+		doc.root = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+	if doc.root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%v : the config file must be a yaml mapping", path)
+	}
+	return doc, nil
+}
+
+// mappingValue returns the value node of key in a mapping node, nil if missing
+// Keys are case insensitive, as read by viper
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// repositories returns the sequence node of the repositories, created if missing
+func (doc *configDocument) repositories() *yaml.Node {
+	mapping := doc.root.Content[0]
+	repos := mappingValue(mapping, "repositories")
+	if repos == nil || repos.Kind != yaml.SequenceNode {
+		if repos == nil {
+			repos = &yaml.Node{}
+			mapping.Content = append(mapping.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "repositories"}, repos)
+		}
+		*repos = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+	return repos
+}
+
+func (doc *configDocument) addRepository(entry repositoryEntry) error {
+	var node yaml.Node
+	if err := node.Encode(entry); err != nil {
+		return err
+	}
+	repos := doc.repositories()
+	repos.Content = append(repos.Content, &node)
+	return nil
+}
+
+func (doc *configDocument) save() error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc.root); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(doc.path, buf.Bytes(), 0600)
+}
+
+// repository returns the mapping node of the repository named name, nil if missing
+func (doc *configDocument) repository(name string) *yaml.Node {
+	for _, repo := range doc.repositories().Content {
+		if value := mappingValue(repo, "name"); value != nil && value.Value == name {
+			return repo
+		}
+	}
+	return nil
+}
+
+func (doc *configDocument) removeRepository(name string) bool {
+	repos := doc.repositories()
+	for i, repo := range repos.Content {
+		if value := mappingValue(repo, "name"); value != nil && value.Value == name {
+			repos.Content = append(repos.Content[:i], repos.Content[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// labels returns the sequence node of the labels of a repository node, created if missing
+func labels(repo *yaml.Node) *yaml.Node {
+	labels := mappingValue(repo, "labels")
+	if labels == nil {
+		labels = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		repo.Content = append(repo.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "labels"}, labels)
+	}
+	return labels
+}
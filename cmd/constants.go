@@ -15,6 +15,11 @@ __from_values()
 {
 	COMPREPLY=( $( compgen -W "`+strings.Join(git.FromArgs, " ")+`" -- "$cur" ) )
 }
+
+__to_values()
+{
+	COMPREPLY=( $( compgen -W "`+strings.Join(git.ToArgs, " ")+`" -- "$cur" ) )
+}
 `
 
 
@@ -0,0 +1,426 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/ttauveron/git-follow-up/followup"
+	"github.com/ttauveron/git-follow-up/git"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Synchronizes git repositories in the background on a schedule",
+	Long: `Synchronizes git repositories in the background on a schedule
+Repositories are synced at startup, then at the given interval or on the given cron expression.
+Schedules of the config file target repositories by name or label, the other repositories using the default schedule.
+
+The status of the daemon and of each repository is written to a JSON state file.
+With --addr, the atom feed of the commits is served on /feed.atom, and push webhooks of GitHub, GitLab and Gitea
+received on /webhook sync the pushed repository, once a webhook secret is configured.
+With --metrics-addr, prometheus metrics of the syncs are served on /metrics of a separate listener.
+SIGTERM and SIGINT stop the daemon once the current sync is finished.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		daemonConfig := config.Daemon
+		// Flags take precedence over the default schedule of the config file
+		if cmd.Flags().Changed("interval") || cmd.Flags().Changed("cron") {
+			daemonConfig.Interval, _ = cmd.Flags().GetString("interval")
+			daemonConfig.Cron, _ = cmd.Flags().GetString("cron")
+		}
+		if daemonConfig.Interval == "" && daemonConfig.Cron == "" {
+			daemonConfig.Interval = defaultSyncInterval
+		}
+		stateFlag, _ := cmd.Flags().GetString("state-file")
+		stateFile, err := daemonStateFile(stateFlag)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		daemonConfig.StateFile = stateFile
+
+		d, err := newDaemon(daemonConfig, config.Repositories)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+
+		// A single daemon runs at a time
+		lock, err := git.TryLock(filepath.Join(configPath, "daemon.lock"))
+		if lockedErr, ok := err.(*git.LockedError); ok {
+			fmt.Printf("Another daemon is running (PID %d)\n", lockedErr.PID)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+
+		if metricsAddr, _ := cmd.Flags().GetString("metrics-addr"); metricsAddr != "" {
+			repoMetrics = newSyncMetrics(config.Repositories)
+			if err := repoMetrics.serve(metricsAddr); err != nil {
+				fmt.Printf("%v\n", err)
+				_ = lock.Release()
+				os.Exit(1)
+			}
+		}
+
+		// Serves the atom feed, and syncs the repositories notified by push webhooks out of their schedule
+		var receiver *webhookReceiver
+		if addr, _ := cmd.Flags().GetString("addr"); addr != "" {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/feed.atom", d.server.handleFeed)
+			if config.Webhook.Secret != "" {
+				receiver, err = newWebhookReceiver(config.Webhook, d.syncPushed)
+				if err != nil {
+					fmt.Printf("%v\n", err)
+					_ = lock.Release()
+					os.Exit(1)
+				}
+				mux.Handle("/webhook", receiver)
+			}
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				_ = lock.Release()
+				os.Exit(1)
+			}
+			fmt.Printf("Listening on %s\n", addr)
+			go func() {
+				if err := http.Serve(listener, mux); err != nil {
+					fmt.Printf("%v\n", err)
+				}
+			}()
+		}
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+		d.run(stop)
+		if receiver != nil {
+			receiver.stop()
+		}
+
+		_ = lock.Release()
+	},
+}
+
+func init() {
+	daemonCmd.Flags().String("interval", "", "default sync interval (e.g. 15m, 1h), for repositories matching no schedule of the config file (default "+defaultSyncInterval+")")
+	daemonCmd.Flags().String("cron", "", "default sync cron expression (e.g. \"*/30 8-19 * * 1-5\"), instead of the interval")
+	daemonCmd.Flags().String("addr", "", "address to listen on for push webhooks and the atom feed (e.g. :8081), disabled by default")
+	daemonCmd.Flags().String("metrics-addr", "", "address to serve the prometheus metrics of the syncs on, under /metrics (e.g. :9100), disabled by default")
+	daemonCmd.Flags().String("state-file", "", "file the daemon status is written to (default is $HOME/.git-follow-up/daemon.json)")
+	rootCmd.AddCommand(daemonCmd)
+}
+
+const defaultSyncInterval = "15m"
+
+// DaemonConfig holds the sync schedules of the daemon command
+type DaemonConfig struct {
+	// Default schedule, of the repositories matching no other schedule
+	Interval  string
+	Cron      string
+	StateFile string `mapstructure:"state_file"`
+	// Schedules of repositories selected by name or label, the first matching schedule applying
+	Schedules []git.Schedule
+}
+
+// withDefaults merges the settings of another config file, the current settings taking precedence
+func (c DaemonConfig) withDefaults(defaults DaemonConfig) DaemonConfig {
+	if c.Interval == "" && c.Cron == "" {
+		c.Interval = defaults.Interval
+		c.Cron = defaults.Cron
+	}
+	if c.StateFile == "" {
+		c.StateFile = defaults.StateFile
+	}
+	c.Schedules = append(c.Schedules, defaults.Schedules...)
+	return c
+}
+
+// daemonState is the content of the state file
+type daemonState struct {
+	PID          int                         `json:"pid"`
+	Status       string                      `json:"status"`
+	Started      time.Time                   `json:"started"`
+	Updated      time.Time                   `json:"updated"`
+	Schedules    []scheduleState             `json:"schedules"`
+	Repositories map[string]*repositoryState `json:"repositories"`
+}
+
+type scheduleState struct {
+	Schedule     string     `json:"schedule"`
+	Repositories []string   `json:"repositories"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	NextRun      time.Time  `json:"next_run"`
+}
+
+type repositoryState struct {
+	LastSync    time.Time  `json:"last_sync"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Duration    string     `json:"duration"`
+	Error       string     `json:"error,omitempty"`
+}
+
+const (
+	daemonIdle    = "idle"
+	daemonSyncing = "syncing"
+	daemonStopped = "stopped"
+)
+
+type daemon struct {
+	schedules []git.Schedule
+	// Repositories of each schedule
+	repos     [][]git.Repository
+	stateFile string
+	// Serves the feed, while no sync is running
+	server *apiServer
+	// Guards the state and the next runs, also updated by webhook syncs
+	mutex sync.Mutex
+	next  []time.Time
+	state daemonState
+}
+
+// newDaemon assigns each repository to its first matching schedule, the default one being last
+func newDaemon(daemonConfig DaemonConfig, repositories []git.Repository) (*daemon, error) {
+	defaultSchedule := git.Schedule{Interval: daemonConfig.Interval, Cron: daemonConfig.Cron}
+	schedules := append(append([]git.Schedule{}, daemonConfig.Schedules...), defaultSchedule)
+	for _, schedule := range schedules {
+		if err := schedule.Validate(); err != nil {
+			return nil, fmt.Errorf("schedule of %v : %v", schedule, err)
+		}
+	}
+
+	d := &daemon{
+		stateFile: daemonConfig.StateFile,
+		server:    &apiServer{},
+		state: daemonState{
+			PID:          os.Getpid(),
+			Started:      time.Now(),
+			Repositories: make(map[string]*repositoryState),
+		},
+	}
+	repos := make([][]git.Repository, len(schedules))
+	for _, repo := range repositories {
+		for i, schedule := range schedules {
+			if schedule.Matches(repo) {
+				repos[i] = append(repos[i], repo)
+				break
+			}
+		}
+	}
+	for i, schedule := range schedules {
+		if len(repos[i]) == 0 {
+			if i < len(schedules)-1 {
+				fmt.Printf("Schedule of %v matches no repository\n", schedule)
+			}
+			continue
+		}
+		d.schedules = append(d.schedules, schedule)
+		d.repos = append(d.repos, repos[i])
+		var names []string
+		for _, repo := range repos[i] {
+			names = append(names, repo.Name)
+		}
+		d.state.Schedules = append(d.state.Schedules, scheduleState{Schedule: schedule.String(), Repositories: names})
+	}
+	if len(d.schedules) == 0 {
+		return nil, fmt.Errorf("no repository to sync")
+	}
+
+	// Keeps the sync history of a previous run
+	if previous, err := readDaemonState(d.stateFile); err == nil && previous.Repositories != nil {
+		d.state.Repositories = previous.Repositories
+	}
+	return d, nil
+}
+
+// daemonStateFile returns the path of the state file, set by flag, by the config file or by default
+func daemonStateFile(flagValue string) (string, error) {
+	stateFile := config.Daemon.StateFile
+	if flagValue != "" {
+		stateFile = flagValue
+	}
+	if stateFile == "" {
+		stateFile = filepath.Join(configPath, "daemon.json")
+	}
+	return homedir.Expand(stateFile)
+}
+
+func readDaemonState(path string) (*daemonState, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state daemonState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("%v : %v", path, err)
+	}
+	return &state, nil
+}
+
+// run syncs the repositories of the due schedules until a signal is received
+func (d *daemon) run(stop <-chan os.Signal) {
+	// Initial sync of every repository
+	d.mutex.Lock()
+	d.next = make([]time.Time, len(d.schedules))
+	now := time.Now()
+	for i := range d.next {
+		d.next[i] = now
+	}
+	d.mutex.Unlock()
+
+	for {
+		d.mutex.Lock()
+		due := d.next[0]
+		for _, next := range d.next[1:] {
+			if next.Before(due) {
+				due = next
+			}
+		}
+		d.mutex.Unlock()
+		d.saveState(daemonIdle)
+
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case sig := <-stop:
+			timer.Stop()
+			fmt.Printf("Received %v, stopping\n", sig)
+			d.saveState(daemonStopped)
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		var repos []git.Repository
+		d.mutex.Lock()
+		for i := range d.schedules {
+			if d.next[i].After(now) {
+				continue
+			}
+			repos = append(repos, d.repos[i]...)
+			d.next[i], _ = d.schedules[i].Next(now)
+			d.state.Schedules[i].LastRun = &now
+		}
+		d.mutex.Unlock()
+		d.saveState(daemonSyncing)
+		fmt.Printf("%v Syncing %d repositories\n", now.Format(time.RFC3339), len(repos))
+
+		var results []followup.SyncResult
+		done := make(chan struct{})
+		go func() {
+			results = d.server.syncResults(repos)
+			close(done)
+		}()
+
+		stopping := false
+		select {
+		case sig := <-stop:
+			fmt.Printf("Received %v, stopping after the current sync...\n", sig)
+			stopping = true
+			<-done
+		case <-done:
+		}
+
+		printSyncSummary(results)
+		d.record(results)
+		notifyNewCommits(results)
+		if stopping {
+			d.saveState(daemonStopped)
+			return
+		}
+	}
+}
+
+// syncPushed syncs repositories notified by webhooks, out of their schedule
+func (d *daemon) syncPushed(repos []git.Repository) {
+	var names []string
+	for _, repo := range repos {
+		names = append(names, repo.Name)
+	}
+	fmt.Printf("%v Syncing %v after a push\n", time.Now().Format(time.RFC3339), strings.Join(names, ", "))
+	results := d.server.syncResults(repos)
+	printSyncSummary(results)
+	d.record(results)
+	d.saveState("")
+	notifyNewCommits(results)
+}
+
+// record updates the state of the synced repositories
+func (d *daemon) record(results []followup.SyncResult) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, result := range results {
+		state, ok := d.state.Repositories[result.Repository.Name]
+		if !ok {
+			state = &repositoryState{}
+			d.state.Repositories[result.Repository.Name] = state
+		}
+		state.LastSync = result.Started
+		state.Duration = result.Duration.Round(time.Millisecond).String()
+		if result.Err != nil {
+			state.Error = result.Err.Error()
+		} else {
+			state.Error = ""
+			finished := result.Started.Add(result.Duration)
+			state.LastSuccess = &finished
+		}
+	}
+}
+
+// saveState writes the state file through a temporary file, so that readers never see it partially written
+// An empty status keeps the current one
+func (d *daemon) saveState(status string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if status != "" {
+		d.state.Status = status
+	}
+	d.state.Updated = time.Now()
+	for i := range d.state.Schedules {
+		if d.next != nil {
+			d.state.Schedules[i].NextRun = d.next[i]
+		}
+	}
+
+	content, err := json.MarshalIndent(d.state, "", "  ")
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	tmp := d.stateFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(content, '\n'), 0600); err != nil {
+		fmt.Printf("Unable to write the state file: %v\n", err)
+		return
+	}
+	if err := os.Rename(tmp, d.stateFile); err != nil {
+		fmt.Printf("Unable to write the state file: %v\n", err)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/ttauveron/git-follow-up/git"
+	"sort"
+	"strings"
+	"time"
+)
+
+// digestCmd represents the digest command
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Summarizes the activity of a period, optionally sent by email",
+	Long: `Summarizes the activity of a period : commits grouped by repository, top contributors, new tags, stale branches and failed syncs
+The digest is rendered as markdown, html or plain text, and sent by email through the smtp server of the config file with --mail-to.
+Meant to run from cron, e.g. weekly with --from wtd, or daily with --from yesterday --to today.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		filter, err = git.NewFilter(cmd.Flags())
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+		format, _ := cmd.Flags().GetString("format")
+		if !git.Contains(git.DigestFormats, format) {
+			fmt.Printf("format flag not recognized, possible values : %s\n", strings.Join(git.DigestFormats, ", "))
+			return
+		}
+		title, _ := cmd.Flags().GetString("title")
+		top, _ := cmd.Flags().GetInt("top")
+		mailTo, _ := cmd.Flags().GetStringSlice("mail-to")
+
+		var staleBefore time.Time
+		if stale, _ := cmd.Flags().GetString("stale"); stale != "" {
+			age, err := git.ParseAge(stale)
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				return
+			}
+			staleBefore = time.Now().Add(-age)
+		}
+
+		repos := filterRepos(*filter)
+		to := filter.To
+		if to.IsZero() {
+			to = time.Now()
+		}
+
+		// Failed syncs of this run, or else of the daemon during the period
+		var failures []git.SyncFailure
+		if doUpdate, _ := cmd.Flags().GetBool("update"); doUpdate {
+			results := syncRepos(repos, nil)
+			printSyncSummary(results)
+			notifyNewCommits(results)
+			for _, result := range results {
+				if result.Err != nil {
+					failures = append(failures, git.SyncFailure{Repository: result.Repository.Name, Date: result.Started, Error: result.Err.Error()})
+				}
+			}
+		} else {
+			failures = daemonSyncFailures(repos, filter.From)
+		}
+
+		digest := git.NewDigest(title, filter.From, to, listCommits(repos, *filter), top)
+		digest.FailedSyncs = failures
+		for _, repo := range repos {
+			tags, err := repo.ListTags()
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				continue
+			}
+			for _, tag := range tags {
+				if !tag.Date.Before(filter.From) && tag.Date.Before(to) {
+					digest.Tags = append(digest.Tags, tag)
+				}
+			}
+
+			if staleBefore.IsZero() {
+				continue
+			}
+			branches, err := repo.ListBranches()
+			if err != nil {
+				fmt.Printf("%v\n", err)
+			}
+			for _, branch := range branches {
+				if !branch.Default && branch.LastCommit.Committer.When.Before(staleBefore) {
+					digest.StaleBranches = append(digest.StaleBranches, branch)
+				}
+			}
+		}
+		sort.Slice(digest.Tags, func(i, j int) bool {
+			return digest.Tags[i].Date.Before(digest.Tags[j].Date)
+		})
+		sort.Sort(git.ByLastCommit(digest.StaleBranches))
+
+		content, err := digest.Render(format)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+
+		if len(mailTo) == 0 {
+			fmt.Print(content)
+			return
+		}
+		contentType := "text/plain"
+		if format == "html" {
+			contentType = "text/html"
+		}
+		subject := fmt.Sprintf("%s (%s - %s)", title, filter.From.Format("2006-01-02"), to.Format("2006-01-02"))
+		if err := config.SMTP.Send(mailTo, subject, contentType, content); err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+		fmt.Printf("Digest sent to %s\n", strings.Join(mailTo, ", "))
+	},
+}
+
+// daemonSyncFailures returns the errors of the last syncs of the daemon since a date
+func daemonSyncFailures(repos []git.Repository, since time.Time) (failures []git.SyncFailure) {
+	stateFile, err := daemonStateFile("")
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return nil
+	}
+	state, err := readDaemonState(stateFile)
+	if err != nil {
+		// The daemon may not be used
+		return nil
+	}
+	for _, repo := range repos {
+		repoState, ok := state.Repositories[repo.Name]
+		if ok && repoState.Error != "" && !repoState.LastSync.Before(since) {
+			failures = append(failures, git.SyncFailure{Repository: repo.Name, Date: repoState.LastSync, Error: repoState.Error})
+		}
+	}
+	return failures
+}
+
+func init() {
+	addFilterFlags(digestCmd.Flags())
+
+	digestCmd.Flags().String("title", "git-follow-up digest", "title of the digest, and subject of the email")
+	digestCmd.Flags().String("format", "markdown", "output format ("+strings.Join(git.DigestFormats, ", ")+")")
+	digestCmd.Flags().Int("top", 5, "number of top contributors")
+	digestCmd.Flags().String("stale", "30d", "lists branches without commits for the given age (e.g. 30d, 2w), disabled if empty")
+	digestCmd.Flags().StringSlice("mail-to", []string{}, "sends the digest by email to these recipients, through the smtp server of the config file")
+	rootCmd.AddCommand(digestCmd)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/ttauveron/git-follow-up/git"
+)
+
+// discoverCmd represents the discover command
+var discoverCmd = &cobra.Command{
+	Use:   "discover <directory>",
+	Short: "Finds git repositories in a directory tree and adds them to the config file",
+	Long: `Finds git repositories in a directory tree, reads their origin remote, and proposes config entries
+Repositories without origin remote are tracked in place, by path.
+Entries are appended to the config file after confirmation, or only printed with --dry-run.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		labels, _ := cmd.Flags().GetStringSlice("label")
+		maxDepth, _ := cmd.Flags().GetInt("max-depth")
+		excludes, _ := cmd.Flags().GetStringSlice("exclude")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		discovered, err := git.DiscoverRepositories(args[0], maxDepth, excludes, labels)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+
+		entries := newEntries(discovered)
+		if len(entries) == 0 {
+			fmt.Println("No new repository found")
+			return
+		}
+
+		addEntries(entries, dryRun, yes)
+	},
+}
+
+func init() {
+	discoverCmd.Flags().StringSlice("label", []string{}, "labels of the discovered repositories")
+	discoverCmd.Flags().Int("max-depth", 3, "maximum depth of the directories to scan (-1 for unlimited)")
+	discoverCmd.Flags().StringSlice("exclude", []string{}, "glob of directories to skip, by name or relative path (e.g. node_modules, vendor/*)")
+	discoverCmd.Flags().Bool("dry-run", false, "prints the config entries without modifying the config file")
+	discoverCmd.Flags().BoolP("yes", "y", false, "appends to the config file without confirmation")
+	rootCmd.AddCommand(discoverCmd)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/ttauveron/git-follow-up/git"
+	"net/http"
+	"time"
+)
+
+// handleFeed serves the commits matching the query parameters as an atom feed
+// Feed readers keeping the entries, the feed covers the last month by default
+func (s *apiServer) handleFeed(w http.ResponseWriter, r *http.Request) {
+	selfUrl := requestUrl(r)
+	query := r.URL.Query()
+	if _, ok := query["from"]; !ok {
+		query.Set("from", time.Now().AddDate(0, -1, 0).Format("2006-01-02"))
+		r.URL.RawQuery = query.Encode()
+	}
+
+	f, err := filterFromQuery(query)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	commits, ok := s.queryCommits(w, r)
+	if !ok {
+		return
+	}
+
+	content, err := git.NewFeed(*f, selfUrl, commits).Xml()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if _, err := w.Write(content); err != nil {
+		fmt.Printf("%v\n", err)
+	}
+}
+
+// requestUrl returns the url requested by the client, behind a reverse proxy too
+func requestUrl(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
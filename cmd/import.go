@@ -0,0 +1,174 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/ttauveron/git-follow-up/git"
+	"strings"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Imports the repositories of a hosting provider organization or group",
+	Long: `Imports the repositories of a GitHub or Gitea organization, or of a GitLab group, into the config file
+Repository topics are added to the labels. Archived repositories are skipped.
+`,
+}
+
+func addImportCmd(provider string, ownerFlag string, ownerUsage string) {
+	cmd := &cobra.Command{
+		Use:   provider,
+		Short: "Imports the repositories of a " + provider + " " + ownerFlag,
+		Run: func(cmd *cobra.Command, args []string) {
+			owner, _ := cmd.Flags().GetString(ownerFlag)
+			importRepositories(cmd, provider, owner)
+		},
+	}
+	cmd.Flags().String(ownerFlag, "", ownerUsage)
+	_ = cmd.MarkFlagRequired(ownerFlag)
+	importCmd.AddCommand(cmd)
+}
+
+func importRepositories(cmd *cobra.Command, kind string, owner string) {
+	baseUrl, _ := cmd.Flags().GetString("base-url")
+	token, _ := cmd.Flags().GetString("token")
+	labels, _ := cmd.Flags().GetStringSlice("label")
+	protocol, _ := cmd.Flags().GetString("protocol")
+	authType, _ := cmd.Flags().GetString("auth-type")
+	authToken, _ := cmd.Flags().GetString("auth-token")
+	syncConfig, _ := cmd.Flags().GetBool("sync-config")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	if protocol != "https" && protocol != "ssh" {
+		fmt.Println("protocol flag not recognized, possible values : https, ssh")
+		return
+	}
+
+	provider, err := git.NewProvider(kind, baseUrl, token)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	remoteRepos, err := provider.ListRepositories(owner)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+
+	var repos []git.Repository
+	for _, remote := range remoteRepos {
+		if remote.Archived {
+			continue
+		}
+		// Names are used as directory names of the local copies
+		name := git.SanitizeName(remote.Name)
+		if name == "" {
+			fmt.Printf("%v skipped : %v\n", remote.CloneUrl, git.ValidateName(remote.Name))
+			continue
+		}
+		repo := git.Repository{
+			Name:   name,
+			Url:    remote.CloneUrl,
+			Labels: append(append([]string{}, labels...), remote.Topics...),
+		}
+		if protocol == "ssh" {
+			repo.Url = remote.SshUrl
+		}
+		repo.Authentication.Type = authType
+		repo.Authentication.Token = authToken
+		repos = append(repos, repo)
+	}
+
+	if syncConfig {
+		// GitLab groups given by id are compared by their full path
+		ownerPath, err := provider.OwnerPath(owner)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+		flagRemovedRepositories(remoteRepos, ownerPath)
+	}
+
+	entries := newEntries(repos)
+	if len(entries) == 0 {
+		fmt.Println("No new repository found")
+		return
+	}
+	addEntries(entries, dryRun, yes)
+}
+
+// flagRemovedRepositories reports the tracked repositories of owner which are archived or deleted
+func flagRemovedRepositories(remoteRepos []git.RemoteRepository, owner string) {
+	remotes := make(map[string]git.RemoteRepository)
+	var hosts []string
+	for _, remote := range remoteRepos {
+		for _, url := range []string{remote.CloneUrl, remote.SshUrl} {
+			if url == "" {
+				continue
+			}
+			normalized := git.NormalizeUrl(url)
+			remotes[normalized] = remote
+			if host := strings.SplitN(normalized, "/", 2)[0]; !git.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+
+	for _, repo := range config.Repositories {
+		if repo.Url == "" {
+			continue
+		}
+		normalized := git.NormalizeUrl(repo.Url)
+		remote, ok := remotes[normalized]
+		switch {
+		case ok && remote.Archived:
+			fmt.Printf("\033[1;33mArchived\033[0m %s (%s)\n", repo.Name, repo.Url)
+		case !ok && belongsTo(normalized, hosts, owner):
+			fmt.Printf("\033[1;31mDeleted\033[0m %s (%s)\n", repo.Name, repo.Url)
+		}
+	}
+}
+
+// belongsTo returns whether a normalized url is the url of a repository of owner, given by its path, on one of hosts
+func belongsTo(normalizedUrl string, hosts []string, owner string) bool {
+	for _, host := range hosts {
+		if strings.HasPrefix(normalizedUrl, host+"/"+strings.ToLower(owner)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	importCmd.PersistentFlags().String("base-url", "", "base url of the provider api (default is the public instance)")
+	importCmd.PersistentFlags().String("token", "", "api token source : file:<path>, env:<VAR> or cmd:<command>")
+	importCmd.PersistentFlags().StringSlice("label", []string{}, "labels of the imported repositories, in addition to their topics")
+	importCmd.PersistentFlags().String("protocol", "https", "clone url protocol (https, ssh)")
+	importCmd.PersistentFlags().String("auth-type", "", "authentication type of the imported repositories (ssh, ssh_agent, access_token)")
+	importCmd.PersistentFlags().String("auth-token", "", "access token source of the imported repositories")
+	importCmd.PersistentFlags().Bool("sync-config", false, "also reports tracked repositories archived or deleted from the organization")
+	importCmd.PersistentFlags().Bool("dry-run", false, "prints the config entries without modifying the config file")
+	importCmd.PersistentFlags().BoolP("yes", "y", false, "appends to the config file without confirmation")
+
+	addImportCmd("github", "org", "GitHub organization")
+	addImportCmd("gitlab", "group", "GitLab group, full path or id")
+	addImportCmd("gitea", "org", "Gitea organization")
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestBelongsTo(t *testing.T) {
+	hosts := []string{"gitlab.com"}
+	tests := []struct {
+		url   string
+		owner string
+		want  bool
+	}{
+		{url: "gitlab.com/platform/sub/api", owner: "platform/sub", want: true},
+		{url: "gitlab.com/platform/sub/deep/api", owner: "Platform/Sub", want: true},
+		{url: "gitlab.com/platform/api", owner: "platform/sub", want: false},
+		{url: "gitlab.com/platform/subway/api", owner: "platform/sub", want: false},
+		{url: "github.com/platform/sub/api", owner: "platform/sub", want: false},
+		// Groups given by id have to be resolved to their full path first
+		{url: "gitlab.com/platform/sub/api", owner: "42", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url+" "+tt.owner, func(t *testing.T) {
+			if got := belongsTo(tt.url, hosts, tt.owner); got != tt.want {
+				t.Errorf("belongsTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/ttauveron/git-follow-up/git"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// issuesCmd represents the issues command
+var issuesCmd = &cobra.Command{
+	Use:   "issues",
+	Short: "Lists issues referenced by commit messages",
+	Long: `Lists issues referenced by commit messages, with the commits, repos and authors that touched them
+Issue keys are detected with the issue_trackers patterns of each repo (PROJ-123 and #456 by default).
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		filter, err = git.NewFilter(cmd.Flags())
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+
+		// Sync repos if update flag is provided
+		doUpdate, err := cmd.Flags().GetBool("update")
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
+
+		if doUpdate {
+			updateCmd.Run(cmd, args)
+		}
+
+		issues := git.GroupByIssue(listCommits(filterRepos(*filter), *filter))
+
+		// initialize tabwriter
+		w := new(tabwriter.Writer)
+		defer w.Flush()
+
+		// minwidth, tabwidth, padding, padchar, flags
+		w.Init(os.Stdout, 8, 8, 0, ' ', 0)
+		for _, issue := range issues {
+			fmt.Fprintf(w, "\033[1;33m%s\033[0m %s\n", issue.Key, issue.Link)
+			fmt.Fprintf(w, "  repos: %s\n", strings.Join(issue.Repos(), ", "))
+			fmt.Fprintf(w, "  authors: %s\n", strings.Join(issue.Authors(), ", "))
+			for _, commit := range issue.Commits {
+				fmt.Fprintln(w, "  "+formatCommit(commit))
+			}
+			fmt.Fprintln(w)
+		}
+	},
+}
+
+func init() {
+	addFilterFlags(issuesCmd.Flags())
+
+	annotation := make(map[string][]string)
+	annotation[cobra.BashCompCustom] = []string{"__display_values"}
+	issuesCmd.Flags().StringSlice("display", []string{}, "commit fields to be displayed")
+	flag := issuesCmd.Flags().Lookup("display")
+	flag.Annotations = annotation
+
+	rootCmd.AddCommand(issuesCmd)
+}
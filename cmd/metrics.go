@@ -0,0 +1,151 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/ttauveron/git-follow-up/followup"
+	"github.com/ttauveron/git-follow-up/git"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// repoMetrics are the metrics of the syncs, nil unless exported with --metrics-addr or --metrics-textfile
+var repoMetrics *syncMetrics
+
+// syncMetrics are the prometheus metrics of the syncs of the tracked repositories
+type syncMetrics struct {
+	registry     *prometheus.Registry
+	duration     *prometheus.GaugeVec
+	success      *prometheus.GaugeVec
+	lastSuccess  *prometheus.GaugeVec
+	fetched      *prometheus.GaugeVec
+	commits      *prometheus.GaugeVec
+	labelCommits *prometheus.GaugeVec
+
+	mutex sync.Mutex
+	repos []git.Repository
+	// Commit count of each repository, summed by label
+	repoCommits map[string]int
+}
+
+// newSyncMetrics registers the metrics, the last successful syncs and commit counts being read from the local copies
+func newSyncMetrics(repos []git.Repository) *syncMetrics {
+	gauge := func(name string, help string, label string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "git_follow_up",
+			Name:      name,
+			Help:      help,
+		}, []string{label})
+	}
+	m := &syncMetrics{
+		registry:     prometheus.NewRegistry(),
+		duration:     gauge("sync_duration_seconds", "Duration of the last sync of the repository.", "repository"),
+		success:      gauge("sync_success", "Whether the last sync of the repository succeeded (1) or failed (0).", "repository"),
+		lastSuccess:  gauge("last_successful_sync_timestamp_seconds", "Unix time of the last successful sync of the repository.", "repository"),
+		fetched:      gauge("fetched_objects", "Number of git objects fetched by the last sync of the repository.", "repository"),
+		commits:      gauge("commits", "Number of commits of the repository.", "repository"),
+		labelCommits: gauge("label_commits", "Number of commits of the repositories with the label.", "label"),
+		repos:        repos,
+		repoCommits:  make(map[string]int),
+	}
+	m.registry.MustRegister(m.duration, m.success, m.lastSuccess, m.fetched, m.commits, m.labelCommits)
+
+	for _, repo := range repos {
+		lastSync, err := repo.LastSync()
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			continue
+		}
+		// Mirrors not synced yet have no commits to count
+		if lastSync.IsZero() && !repo.IsLocal() {
+			continue
+		}
+		if !lastSync.IsZero() {
+			m.lastSuccess.WithLabelValues(repo.Name).Set(float64(lastSync.Unix()))
+		}
+		m.countCommits(repo)
+	}
+	m.sumLabelCommits()
+	return m
+}
+
+// record updates the metrics of the synced repositories
+func (m *syncMetrics) record(results []followup.SyncResult) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, result := range results {
+		name := result.Repository.Name
+		m.duration.WithLabelValues(name).Set(result.Duration.Seconds())
+		if result.Err != nil {
+			m.success.WithLabelValues(name).Set(0)
+			continue
+		}
+		m.success.WithLabelValues(name).Set(1)
+		m.lastSuccess.WithLabelValues(name).Set(float64(result.Started.Add(result.Duration).Unix()))
+		m.fetched.WithLabelValues(name).Set(float64(result.Fetched))
+		m.countCommits(result.Repository)
+	}
+	m.sumLabelCommits()
+}
+
+func (m *syncMetrics) countCommits(repo git.Repository) {
+	count, err := repo.CountCommits()
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	m.repoCommits[repo.Name] = count
+	m.commits.WithLabelValues(repo.Name).Set(float64(count))
+}
+
+func (m *syncMetrics) sumLabelCommits() {
+	labelCommits := make(map[string]int)
+	for _, repo := range m.repos {
+		for _, label := range repo.Labels {
+			labelCommits[label] += m.repoCommits[repo.Name]
+		}
+	}
+	for label, count := range labelCommits {
+		m.labelCommits.WithLabelValues(label).Set(float64(count))
+	}
+}
+
+// serve exposes the metrics on /metrics of addr, in the background
+func (m *syncMetrics) serve(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	fmt.Printf("Serving metrics on %s/metrics\n", addr)
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			fmt.Printf("%v\n", err)
+		}
+	}()
+	return nil
+}
+
+// writeTextfile writes the metrics for the textfile collector of the node exporter
+func (m *syncMetrics) writeTextfile(path string) error {
+	return prometheus.WriteToTextfile(path, m.registry)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/ttauveron/git-follow-up/followup"
+	"github.com/ttauveron/git-follow-up/git"
+)
+
+// notifyNewCommits sends the commits fetched by the syncs to the notifications they match
+func notifyNewCommits(results []followup.SyncResult) {
+	notifier := git.Notifier{SMTP: config.SMTP}
+	for _, notification := range config.Notifications {
+		var commits []git.Commit
+		for _, result := range results {
+			for _, c := range result.NewCommits {
+				matched, err := notification.Matches(result.Repository, c)
+				if err != nil {
+					fmt.Printf("notification %v : %v : %v\n", notification.Name, c.Name, err)
+					continue
+				}
+				if matched {
+					commits = append(commits, c)
+				}
+			}
+		}
+		if len(commits) == 0 {
+			continue
+		}
+
+		errors := notifier.Notify(notification, commits)
+		fmt.Printf("notification %v : %d commits sent to %d/%d targets\n", notification.Name, len(commits), len(notification.Targets)-len(errors), len(notification.Targets))
+		for _, err := range errors {
+			fmt.Printf("\033[1;31mFailed\033[0m %v\n", err)
+		}
+	}
+}
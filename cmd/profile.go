@@ -0,0 +1,56 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"sort"
+	"strings"
+)
+
+// applyProfile sets the flags of cmd from the default values of a profile.
+// Flags given on the command line take precedence, and profile values unknown to cmd are ignored.
+// Profile names are case insensitive, the config keys being lowercased when read.
+func applyProfile(cmd *cobra.Command, name string) error {
+	profile, ok := config.Profiles[strings.ToLower(name)]
+	if !ok {
+		var names []string
+		for n := range config.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("profile %q not found (%s)", name, strings.Join(names, ", "))
+	}
+
+	for key, value := range profile {
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil || flag.Changed {
+			continue
+		}
+
+		values := []interface{}{value}
+		if list, ok := value.([]interface{}); ok {
+			values = list
+		}
+		for _, v := range values {
+			if err := cmd.Flags().Set(key, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("profile %q : %v : %v", name, key, err)
+			}
+		}
+	}
+	return nil
+}
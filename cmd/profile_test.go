@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// withConfigFiles writes config files in a temp dir, with a fresh config restored once the test ends
+func withConfigFiles(t *testing.T, files map[string]string) string {
+	dir := writeConfigFiles(t, files)
+	savedConfig, savedFiles := config, configFiles
+	config, configFiles = Config{}, nil
+	t.Cleanup(func() {
+		config, configFiles = savedConfig, savedFiles
+	})
+	return dir
+}
+
+func TestApplyProfile(t *testing.T) {
+	dir := withConfigFiles(t, map[string]string{
+		"config.yaml": `
+include: [team.yaml]
+profiles:
+  standup:
+    from: yesterday
+    label: [work, go]
+`,
+		"team.yaml": `
+profiles:
+  standup:
+    from: mtd
+  review:
+    from: wtd
+    author: [jean]
+  Retro:
+    from: mtd
+`,
+	})
+	loader := newConfigLoader()
+	if err := loader.load(filepath.Join(dir, "config.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	config = loader.config
+
+	tests := []struct {
+		name       string
+		profile    string
+		args       []string
+		wantFrom   string
+		wantLabels []string
+		wantAuthor []string
+		wantErr    bool
+	}{
+		{name: "defaults", profile: "standup", wantFrom: "yesterday", wantLabels: []string{"work", "go"}, wantAuthor: []string{}},
+		{name: "explicit flag", profile: "standup", args: []string{"--from", "today"}, wantFrom: "today", wantLabels: []string{"work", "go"}, wantAuthor: []string{}},
+		{name: "included profile", profile: "review", wantFrom: "wtd", wantLabels: []string{}, wantAuthor: []string{"jean"}},
+		{name: "mixed case profile", profile: "Retro", wantFrom: "mtd", wantLabels: []string{}, wantAuthor: []string{}},
+		{name: "profile in another case", profile: "STANDUP", wantFrom: "yesterday", wantLabels: []string{"work", "go"}, wantAuthor: []string{}},
+		{name: "unknown profile", profile: "planning", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "commits"}
+			addFilterFlags(cmd.Flags())
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := applyProfile(cmd, tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			from, _ := cmd.Flags().GetString("from")
+			labels, _ := cmd.Flags().GetStringSlice("label")
+			authors, _ := cmd.Flags().GetStringSlice("author")
+			if from != tt.wantFrom {
+				t.Errorf("from = %v, want %v", from, tt.wantFrom)
+			}
+			if !reflect.DeepEqual(labels, tt.wantLabels) {
+				t.Errorf("label = %v, want %v", labels, tt.wantLabels)
+			}
+			if !reflect.DeepEqual(authors, tt.wantAuthor) {
+				t.Errorf("author = %v, want %v", authors, tt.wantAuthor)
+			}
+		})
+	}
+}
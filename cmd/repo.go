@@ -0,0 +1,291 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/mitchellh/go-homedir"
+	"github.com/ttauveron/git-follow-up/git"
+	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// repoCmd represents the repo command
+var repoCmd = &cobra.Command{
+	Use:   "repo",
+	Short: "Manages the repositories of the config file",
+	Long: `Manages the repositories of the config file
+The config file is edited in place, preserving its comments and ordering.
+`,
+}
+
+var repoAddCmd = &cobra.Command{
+	Use:   "add <name> [url]",
+	Short: "Adds a repository to the config file",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo := git.Repository{Name: args[0]}
+		if len(args) > 1 {
+			repo.Url = args[1]
+		}
+		repo.Path, _ = cmd.Flags().GetString("path")
+		repo.Labels, _ = cmd.Flags().GetStringSlice("label")
+		repo.Authentication.Type, _ = cmd.Flags().GetString("auth-type")
+		repo.Authentication.AuthFile, _ = cmd.Flags().GetString("auth-file")
+		repo.Authentication.Token, _ = cmd.Flags().GetString("auth-token")
+
+		if err := addRepository(repo); err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+		fmt.Printf("Repository %v added\n", repo.Name)
+	},
+}
+
+// addRepository validates a repository and appends it to the first config file
+func addRepository(repo git.Repository) error {
+	if err := git.ValidateName(repo.Name); err != nil {
+		return err
+	}
+	switch {
+	case repo.Url != "" && repo.Path != "":
+		return fmt.Errorf("url and path flag are mutually exclusive")
+	case repo.Path == "":
+		if err := git.ValidateUrl(repo.Url); err != nil {
+			return err
+		}
+		break
+	default:
+		// Relative paths are resolved against the current directory, not the one of later runs
+		path, err := homedir.Expand(repo.Path)
+		if err != nil {
+			return err
+		}
+		if repo.Path, err = filepath.Abs(path); err != nil {
+			return err
+		}
+		if _, err := gogit.PlainOpen(repo.Path); err != nil {
+			return fmt.Errorf("%v : %v", repo.Path, err)
+		}
+		break
+	}
+	if repo.Authentication.Type != "" && !git.Contains(git.AuthTypes, repo.Authentication.Type) {
+		return fmt.Errorf("auth-type flag not recognized, possible values : %s", strings.Join(git.AuthTypes, ", "))
+	}
+
+	// Repositories of the included files too
+	if _, ok := findRepository(repo.Name); ok {
+		return fmt.Errorf("repository %v already exists", repo.Name)
+	}
+	doc, err := loadConfigDocument(configFileUsed())
+	if err != nil {
+		return err
+	}
+	if doc.repository(repo.Name) != nil {
+		return fmt.Errorf("repository %v already exists", repo.Name)
+	}
+	if err := doc.addRepository(newRepositoryEntry(repo)); err != nil {
+		return err
+	}
+	return doc.save()
+}
+
+var repoRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Removes a repository from the config file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		purge, _ := cmd.Flags().GetBool("purge")
+
+		doc, err := loadConfigDocument(configFileUsed())
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+		if !doc.removeRepository(args[0]) {
+			fmt.Printf("repository %v not found\n", args[0])
+			return
+		}
+		if err := doc.save(); err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+		fmt.Printf("Repository %v removed\n", args[0])
+
+		if purge {
+			repo, ok := findRepository(args[0])
+			// Local clones tracked in place are never deleted
+			if !ok || repo.IsLocal() {
+				return
+			}
+			if err := os.RemoveAll(repo.LocalPath); err != nil {
+				fmt.Printf("%v\n", err)
+				return
+			}
+			fmt.Printf("Local copy %v deleted\n", repo.LocalPath)
+		}
+	},
+}
+
+var repoListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the repositories of the config file",
+	Run: func(cmd *cobra.Command, args []string) {
+		labels, _ := cmd.Flags().GetStringSlice("label")
+
+		// initialize tabwriter
+		w := new(tabwriter.Writer)
+		defer w.Flush()
+
+		// minwidth, tabwidth, padding, padchar, flags
+		w.Init(os.Stdout, 8, 8, 1, ' ', 0)
+		for _, repo := range config.Repositories {
+			if !git.ContainsAll(repo.Labels, labels) {
+				continue
+			}
+			location := repo.Url
+			if repo.Path != "" {
+				location = repo.Path
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", repo.Name, location, strings.Join(repo.Labels, ","))
+		}
+	},
+}
+
+var repoShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Shows the config entry and local copy of a repository",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		doc, err := loadConfigDocument(configFileUsed())
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+		node := doc.repository(args[0])
+		if node == nil {
+			fmt.Printf("repository %v not found\n", args[0])
+			return
+		}
+		out, err := yaml.Marshal(node)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+		fmt.Print(string(out))
+
+		if repo, ok := findRepository(args[0]); ok {
+			status := "not synced"
+			if _, err := os.Stat(repo.LocalPath); err == nil {
+				status = "synced"
+			}
+			fmt.Printf("# local copy: %s (%s)\n", repo.LocalPath, status)
+		}
+	},
+}
+
+var repoLabelCmd = &cobra.Command{
+	Use:   "label",
+	Short: "Adds or removes labels of a repository",
+}
+
+var repoLabelAddCmd = &cobra.Command{
+	Use:   "add <name> <label>...",
+	Short: "Adds labels to a repository",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		editLabels(args[0], func(labels *yaml.Node) {
+			for _, label := range args[1:] {
+				if indexOfScalar(labels, label) < 0 {
+					labels.Content = append(labels.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: label})
+				}
+			}
+		})
+	},
+}
+
+var repoLabelRemoveCmd = &cobra.Command{
+	Use:     "rm <name> <label>...",
+	Aliases: []string{"remove"},
+	Short:   "Removes labels from a repository",
+	Args:    cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		editLabels(args[0], func(labels *yaml.Node) {
+			for _, label := range args[1:] {
+				if i := indexOfScalar(labels, label); i >= 0 {
+					labels.Content = append(labels.Content[:i], labels.Content[i+1:]...)
+				}
+			}
+		})
+	},
+}
+
+func editLabels(name string, edit func(labels *yaml.Node)) {
+	doc, err := loadConfigDocument(configFileUsed())
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	repo := doc.repository(name)
+	if repo == nil {
+		fmt.Printf("repository %v not found\n", name)
+		return
+	}
+	edit(labels(repo))
+	if err := doc.save(); err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	fmt.Printf("Labels of %v updated\n", name)
+}
+
+func indexOfScalar(seq *yaml.Node, value string) int {
+	for i, node := range seq.Content {
+		if node.Value == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// findRepository returns the loaded config of the repository named name
+func findRepository(name string) (git.Repository, bool) {
+	for _, repo := range config.Repositories {
+		if repo.Name == name {
+			return repo, true
+		}
+	}
+	return git.Repository{}, false
+}
+
+func init() {
+	repoAddCmd.Flags().String("path", "", "path of an existing local clone, tracked in place")
+	repoAddCmd.Flags().StringSlice("label", []string{}, "labels of the repository")
+	repoAddCmd.Flags().String("auth-type", "", "authentication type ("+strings.Join(git.AuthTypes, ", ")+")")
+	repoAddCmd.Flags().String("auth-file", "", "ssh key or access token file")
+	repoAddCmd.Flags().String("auth-token", "", "access token source : file:<path>, env:<VAR>, cmd:<command>, git-credential or netrc")
+	repoRemoveCmd.Flags().Bool("purge", false, "also deletes the local copy of the repository")
+	repoListCmd.Flags().StringSlice("label", []string{}, "filters by project labels")
+
+	repoLabelCmd.AddCommand(repoLabelAddCmd, repoLabelRemoveCmd)
+	repoCmd.AddCommand(repoAddCmd, repoRemoveCmd, repoListCmd, repoShowCmd, repoLabelCmd)
+	rootCmd.AddCommand(repoCmd)
+}
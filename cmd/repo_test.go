@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"github.com/ttauveron/git-follow-up/git"
+	gogit "gopkg.in/src-d/go-git.v4"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddRepository(t *testing.T) {
+	dir := withConfigFiles(t, map[string]string{
+		"config.yaml": `
+include: [team.yaml]
+repositories:
+  - name: main
+    url: https://github.com/team/main.git
+`,
+		"team.yaml": `
+repositories:
+  - name: foo
+    url: https://github.com/team/foo.git
+`,
+	})
+	loader := newConfigLoader()
+	if err := loader.load(filepath.Join(dir, "config.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	config, configFiles = loader.config, loader.files
+
+	if _, err := gogit.PlainInit(filepath.Join(dir, "src", "clone"), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "src", "notes"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// Relative paths are given from the current directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "src")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		name    string
+		repo    git.Repository
+		wantErr string
+	}{
+		{name: "duplicate of the config file", repo: git.Repository{Name: "main", Url: "https://github.com/team/other.git"}, wantErr: "repository main already exists"},
+		{name: "duplicate of an included file", repo: git.Repository{Name: "foo", Url: "https://github.com/team/other.git"}, wantErr: "repository foo already exists"},
+		{name: "path of a directory", repo: git.Repository{Name: "notes", Path: "notes"}, wantErr: filepath.Join(dir, "src", "notes") + " : repository does not exist"},
+		{name: "missing path", repo: git.Repository{Name: "missing", Path: "missing"}, wantErr: filepath.Join(dir, "src", "missing") + " : repository does not exist"},
+		{name: "relative path", repo: git.Repository{Name: "clone", Path: "./clone"}},
+		{name: "url", repo: git.Repository{Name: "bar", Url: "https://github.com/team/bar.git"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := addRepository(tt.repo)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("addRepository() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"path: " + filepath.Join(dir, "src", "clone"), "name: bar"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("config file =\n%s\nwant %v", content, want)
+		}
+	}
+	team, err := ioutil.ReadFile(filepath.Join(dir, "team.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(team), "bar") {
+		t.Errorf("included file modified :\n%s", team)
+	}
+}
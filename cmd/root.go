@@ -22,13 +22,35 @@ import (
 	"github.com/spf13/viper"
 	"github.com/ttauveron/git-follow-up/git"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
-var cfgFile, configPath, gitPath string
+var configPath, gitPath string
+var cfgFiles []string
+var lockTimeout time.Duration
 var config Config
 
+// Config files loaded, including the included ones
+var configFiles []string
+
 type Config struct {
+	// Config files or globs whose repositories are merged, relative to the including file
+	Include      []string
 	Repositories []git.Repository
+	// Default ssh host key checking of the repositories
+	SSH git.HostKeyChecking `mapstructure:"ssh"`
+	// Default flag values, by profile name
+	Profiles map[string]map[string]interface{}
+	// Sync schedules of the daemon command
+	Daemon DaemonConfig
+	// Push webhooks received by the serve and daemon commands
+	Webhook WebhookConfig
+	// Notifications of the commits fetched by syncs
+	Notifications []git.Notification
+	// Mail server of the email notifications
+	SMTP git.SMTP `mapstructure:"smtp"`
 }
 
 // rootCmd represents the base command when called without any subcommands
@@ -46,6 +68,27 @@ var rootCmd = &cobra.Command{
 Keeps track of contributions made on multiple git repositories described in a yaml configuration file.
 Those repositories can be hosted on any platform, and accessed through ssh, https, with or without an access token.`,
 	BashCompletionFunction: bash_completion_func,
+	// Commands refuse to run with an invalid config file, then get the flags of the selected profile
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		errors, err := validateConfigFiles(configFiles)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+		for _, err := range errors {
+			fmt.Printf("%v\n", err)
+		}
+		if len(errors) > 0 {
+			os.Exit(1)
+		}
+
+		if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+			if err := applyProfile(cmd, profile); err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -60,7 +103,9 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.git-follow-up/config.yaml)")
+	rootCmd.PersistentFlags().String("profile", "", "applies the default flag values of a profile of the config file")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", 5*time.Minute, "how long a sync waits for another process syncing the same repository")
+	rootCmd.PersistentFlags().StringArrayVar(&cfgFiles, "config", []string{}, "config file, can be repeated (default is $GIT_FOLLOW_UP_CONFIG or $HOME/.git-follow-up/config.yaml)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -80,26 +125,148 @@ func initConfig() {
 	// Create repositories folder if not exists
 	_ = os.MkdirAll(configPath+"/git", 0700)
 
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
+	files := cfgFiles
+	if len(files) == 0 && os.Getenv("GIT_FOLLOW_UP_CONFIG") != "" {
+		// Use config files from the environment, separated like $PATH
+		files = filepath.SplitList(os.Getenv("GIT_FOLLOW_UP_CONFIG"))
+	}
+	if len(files) == 0 {
 		viper.AddConfigPath(configPath)
 		viper.SetConfigName("config")
+		//If a config file is found, read it in.
+		if err := viper.ReadInConfig(); err != nil {
+			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+				fmt.Printf("Unable to read config: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			files = append(files, viper.ConfigFileUsed())
+		}
 	}
 
-	//If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		err = viper.Unmarshal(&config)
-		for i := 0; i < len(config.Repositories); i++ {
-			config.Repositories[i].LocalPath = gitPath + config.Repositories[i].Name
+	loader := newConfigLoader()
+	for _, file := range files {
+		if err := loader.load(file); err != nil {
+			fmt.Printf("%v\n", err)
+			// Locate the faulty values
+			errors, _ := validateConfigFiles(loader.files)
+			for _, err := range errors {
+				fmt.Printf("%v\n", err)
+			}
+			os.Exit(1)
 		}
+	}
+	config, configFiles = loader.config, loader.files
 
-		if err != nil {
-			panic("Unable to unmarshal config")
+	for i := 0; i < len(config.Repositories); i++ {
+		localPath, pathErr := config.Repositories[i].ResolveLocalPath(gitPath)
+		if pathErr != nil {
+			fmt.Printf("%v : %v\n", config.Repositories[i].Name, pathErr)
 		}
+		config.Repositories[i].LocalPath = localPath
+		auth := &config.Repositories[i].Authentication
+		auth.HostKeyChecking = auth.HostKeyChecking.WithDefaults(config.SSH)
 	}
 
 	viper.AutomaticEnv() // read in environment variables that match
 
 }
+
+// configLoader reads config files and the files they include, each file once
+type configLoader struct {
+	// Settings of the files read, the first files taking precedence
+	config Config
+	// Files read, in order
+	files  []string
+	loaded map[string]bool
+}
+
+func newConfigLoader() *configLoader {
+	return &configLoader{loaded: make(map[string]bool)}
+}
+
+// load reads a config file and the files it includes, merging their settings into the config of the loader.
+// Files already read, or included in a cycle, are skipped.
+func (l *configLoader) load(path string) error {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	// Already loaded, or included in a cycle
+	if l.loaded[abs] {
+		return nil
+	}
+	l.loaded[abs] = true
+	l.files = append(l.files, path)
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("unable to read config: %v", err)
+	}
+	var fileConfig Config
+	if err := v.Unmarshal(&fileConfig); err != nil {
+		return fmt.Errorf("unable to unmarshal config %v: %v", path, err)
+	}
+	l.config = l.config.withDefaults(fileConfig)
+
+	for _, include := range fileConfig.Include {
+		matches, err := includedFiles(path, include)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			if err := l.load(match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// includedFiles returns the files of an include of the config file path, relative to its directory.
+// Globs may match no file, but plain paths must exist.
+func includedFiles(path string, include string) ([]string, error) {
+	pattern, err := homedir.Expand(include)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(path), pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%v : include %v : %v", path, include, err)
+	}
+	if len(matches) == 0 && !strings.ContainsAny(include, "*?[") {
+		return nil, fmt.Errorf("%v : include %v : file not found", path, include)
+	}
+	return matches, nil
+}
+
+// withDefaults merges the settings of another config file, the current settings taking precedence.
+// Repositories and notifications of both files are kept, profiles are merged by name.
+func (c Config) withDefaults(defaults Config) Config {
+	c.Repositories = append(c.Repositories, defaults.Repositories...)
+	c.SSH = c.SSH.WithDefaults(defaults.SSH)
+	c.Daemon = c.Daemon.withDefaults(defaults.Daemon)
+	c.Webhook = c.Webhook.withDefaults(defaults.Webhook)
+	c.Notifications = append(c.Notifications, defaults.Notifications...)
+	for name, profile := range defaults.Profiles {
+		if _, ok := c.Profiles[name]; ok {
+			continue
+		}
+		if c.Profiles == nil {
+			c.Profiles = make(map[string]map[string]interface{})
+		}
+		c.Profiles[name] = profile
+	}
+	if c.SMTP.Host == "" {
+		c.SMTP = defaults.SMTP
+	}
+	return c
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfigFiles writes config files in a temp dir, removed once the test ends
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestConfigLoader(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantFiles []string
+		wantRepos []string
+		wantSMTP  string
+		wantErr   string
+	}{
+		{
+			name: "globs and relative paths",
+			files: map[string]string{
+				"conf/config.yaml": `
+include: [repos/*.yaml, ../shared.yaml]
+repositories: [{name: main, url: "https://github.com/team/main.git"}]
+smtp: {host: smtp.team.com}
+`,
+				"conf/repos/b.yaml": `repositories: [{name: b, url: "https://github.com/team/b.git"}]`,
+				"conf/repos/a.yaml": `repositories: [{name: a, url: "https://github.com/team/a.git"}]`,
+				"shared.yaml": `
+repositories: [{name: shared, url: "https://github.com/team/shared.git"}]
+smtp: {host: smtp.shared.com}
+`,
+			},
+			wantFiles: []string{"conf/config.yaml", "conf/repos/a.yaml", "conf/repos/b.yaml", "shared.yaml"},
+			wantRepos: []string{"main", "a", "b", "shared"},
+			wantSMTP:  "smtp.team.com",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"conf/config.yaml": `
+include: [team.yaml]
+repositories: [{name: main, url: "https://github.com/team/main.git"}]
+`,
+				"conf/team.yaml": `
+include: [config.yaml, ./team.yaml]
+repositories: [{name: team, url: "https://github.com/team/team.git"}]
+`,
+			},
+			wantFiles: []string{"conf/config.yaml", "conf/team.yaml"},
+			wantRepos: []string{"main", "team"},
+		},
+		{
+			name: "glob matching no file",
+			files: map[string]string{
+				"conf/config.yaml": `
+include: [conf.d/*.yaml]
+repositories: [{name: main, url: "https://github.com/team/main.git"}]
+`,
+			},
+			wantFiles: []string{"conf/config.yaml"},
+			wantRepos: []string{"main"},
+		},
+		{
+			name: "missing plain path",
+			files: map[string]string{
+				"conf/config.yaml": `include: [team.yaml]`,
+			},
+			wantErr: "conf/config.yaml : include team.yaml : file not found",
+		},
+		{
+			name: "missing included file of an include",
+			files: map[string]string{
+				"conf/config.yaml": `include: [team/*.yaml]`,
+				"conf/team/a.yaml": `include: [../b.yaml]`,
+			},
+			wantErr: "conf/team/a.yaml : include ../b.yaml : file not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, tt.files)
+
+			loader := newConfigLoader()
+			err := loader.load(filepath.Join(dir, "conf", "config.yaml"))
+			if tt.wantErr != "" {
+				if err == nil || strings.Replace(err.Error(), dir+string(filepath.Separator), "", -1) != tt.wantErr {
+					t.Fatalf("load() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var files []string
+			for _, file := range loader.files {
+				rel, err := filepath.Rel(dir, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Errorf("files = %v, want %v", files, tt.wantFiles)
+			}
+			var repos []string
+			for _, repo := range loader.config.Repositories {
+				repos = append(repos, repo.Name)
+			}
+			if !reflect.DeepEqual(repos, tt.wantRepos) {
+				t.Errorf("repositories = %v, want %v", repos, tt.wantRepos)
+			}
+			if loader.config.SMTP.Host != tt.wantSMTP {
+				t.Errorf("smtp host = %v, want %v", loader.config.SMTP.Host, tt.wantSMTP)
+			}
+		})
+	}
+}
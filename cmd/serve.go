@@ -0,0 +1,370 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/ttauveron/git-follow-up/followup"
+	"github.com/ttauveron/git-follow-up/git"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves commits, repos and stats over a REST api",
+	Long: `Serves commits, repos and stats over a REST api
+Endpoints accept the filters of the commits command as query parameters, e.g. /api/commits?from=mtd&label=go&author=jean
+
+  /api/commits   commits matching the filters
+  /api/repos     tracked repositories
+  /api/stats     commit counts by repository and author
+  /api/authors   authors of the matching commits
+  /api/diff      patch of a commit, e.g. /api/diff?repo=cobra&hash=0a1b2c3d...
+  /feed.atom     atom feed of the commits matching the filters, of the last month by default
+
+A web ui browsing the tracked activity is served on /.
+Push webhooks of GitHub, GitLab and Gitea received on /webhook sync the pushed repository, once a webhook secret is configured.
+With --metrics-addr, prometheus metrics of the syncs are served on /metrics of a separate listener.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		addr, _ := cmd.Flags().GetString("addr")
+		syncInterval, _ := cmd.Flags().GetDuration("sync-interval")
+
+		server := &apiServer{}
+		// Push webhooks are received once a secret is configured
+		if config.Webhook.Secret != "" {
+			receiver, err := newWebhookReceiver(config.Webhook, server.sync)
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+			server.webhook = receiver
+		}
+		if metricsAddr, _ := cmd.Flags().GetString("metrics-addr"); metricsAddr != "" {
+			repoMetrics = newSyncMetrics(config.Repositories)
+			if err := repoMetrics.serve(metricsAddr); err != nil {
+				fmt.Printf("%v\n", err)
+				os.Exit(1)
+			}
+		}
+		if syncInterval > 0 {
+			go server.syncEvery(syncInterval)
+		}
+
+		fmt.Printf("Listening on %s\n", addr)
+		if err := http.ListenAndServe(addr, server.handler()); err != nil {
+			fmt.Printf("%v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// apiServer serves the api, repositories being read while their references aren't updated by a sync
+type apiServer struct {
+	locks   repoLocks
+	webhook *webhookReceiver
+}
+
+// repoLocks are read-write locks by repository name, read locked by requests and write locked by syncs updating references
+type repoLocks struct {
+	mutex sync.Mutex
+	locks map[string]*sync.RWMutex
+}
+
+func (l *repoLocks) get(name string) *sync.RWMutex {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.locks == nil {
+		l.locks = make(map[string]*sync.RWMutex)
+	}
+	lock, ok := l.locks[name]
+	if !ok {
+		lock = &sync.RWMutex{}
+		l.locks[name] = lock
+	}
+	return lock
+}
+
+// readLock read locks the repositories, returning the function unlocking them
+// Syncs lock a single repository at a time, so that read locks can be taken one after the other
+func (l *repoLocks) readLock(repos []git.Repository) (unlock func()) {
+	var locks []*sync.RWMutex
+	for _, repo := range repos {
+		lock := l.get(repo.Name)
+		lock.RLock()
+		locks = append(locks, lock)
+	}
+	return func() {
+		for _, lock := range locks {
+			lock.RUnlock()
+		}
+	}
+}
+
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/commits", s.handleCommits)
+	mux.HandleFunc("/api/repos", s.handleRepos)
+	mux.HandleFunc("/api/stats", s.handleStats)
+	mux.HandleFunc("/api/authors", s.handleAuthors)
+	mux.HandleFunc("/api/diff", s.handleDiff)
+	mux.HandleFunc("/feed.atom", s.handleFeed)
+	if s.webhook != nil {
+		mux.Handle("/webhook", s.webhook)
+	}
+	mux.Handle("/", webHandler())
+	return mux
+}
+
+func (s *apiServer) syncEvery(interval time.Duration) {
+	for {
+		s.sync(config.Repositories)
+		time.Sleep(interval)
+	}
+}
+
+func (s *apiServer) sync(repos []git.Repository) {
+	updateRepos(repos, &s.locks)
+}
+
+// syncResults syncs repositories, their references being updated once no request reads them
+func (s *apiServer) syncResults(repos []git.Repository) []followup.SyncResult {
+	return syncRepos(repos, &s.locks)
+}
+
+type commitJSON struct {
+	Repository string         `json:"repository"`
+	Hash       string         `json:"hash"`
+	Date       time.Time      `json:"date"`
+	Author     string         `json:"author"`
+	Email      string         `json:"email"`
+	CoAuthors  []string       `json:"co_authors,omitempty"`
+	Subject    string         `json:"subject"`
+	Message    string         `json:"message"`
+	Issues     []git.IssueRef `json:"issues,omitempty"`
+}
+
+func newCommitJSON(c git.Commit) commitJSON {
+	return commitJSON{
+		Repository: c.Name,
+		Hash:       c.Commit.Hash.String(),
+		Date:       c.Commit.Author.When,
+		Author:     c.Commit.Author.Name,
+		Email:      c.Commit.Author.Email,
+		CoAuthors:  git.CoAuthors(c.Commit.Message),
+		Subject:    c.Subject(),
+		Message:    c.Commit.Message,
+		Issues:     c.Issues,
+	}
+}
+
+type repositoryJSON struct {
+	Name   string   `json:"name"`
+	Url    string   `json:"url,omitempty"`
+	Path   string   `json:"path,omitempty"`
+	Labels []string `json:"labels"`
+	Synced bool     `json:"synced"`
+}
+
+type authorJSON struct {
+	Name       string    `json:"name"`
+	Email      string    `json:"email"`
+	Commits    int       `json:"commits"`
+	LastCommit time.Time `json:"last_commit"`
+}
+
+type repositoryStatJSON struct {
+	Name    string `json:"name"`
+	Commits int    `json:"commits"`
+}
+
+type statsJSON struct {
+	Commits      int                  `json:"commits"`
+	Repositories []repositoryStatJSON `json:"repositories"`
+	Authors      []git.AuthorStat     `json:"authors"`
+}
+
+// filterFromQuery reads the filter flags of the commits command from query parameters.
+// The repo parameter, handled by queryRepos, is skipped.
+func filterFromQuery(query url.Values) (*git.Filter, error) {
+	flags := pflag.NewFlagSet("query", pflag.ContinueOnError)
+	addFilterFlags(flags)
+	for key, values := range query {
+		if key == "repo" {
+			continue
+		}
+		if flags.Lookup(key) == nil {
+			return nil, fmt.Errorf("unknown parameter %q", key)
+		}
+		for _, value := range values {
+			if err := flags.Set(key, value); err != nil {
+				return nil, fmt.Errorf("parameter %q : %v", key, err)
+			}
+		}
+	}
+	return git.NewFilter(flags)
+}
+
+// queryRepos returns the repositories matching the label filter, and the repo parameters if any
+func queryRepos(f git.Filter, query url.Values) (repos []git.Repository) {
+	names := query["repo"]
+	for _, repo := range filterRepos(f) {
+		if len(names) == 0 || git.Contains(names, repo.Name) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+// queryCommits lists the commits matching the query parameters
+func (s *apiServer) queryCommits(w http.ResponseWriter, r *http.Request) ([]git.Commit, bool) {
+	f, err := filterFromQuery(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return nil, false
+	}
+
+	repos := queryRepos(*f, r.URL.Query())
+	defer s.locks.readLock(repos)()
+	return listCommits(repos, *f), true
+}
+
+func (s *apiServer) handleCommits(w http.ResponseWriter, r *http.Request) {
+	commits, ok := s.queryCommits(w, r)
+	if !ok {
+		return
+	}
+	result := []commitJSON{}
+	for _, c := range commits {
+		result = append(result, newCommitJSON(c))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *apiServer) handleRepos(w http.ResponseWriter, r *http.Request) {
+	labels := r.URL.Query()["label"]
+	result := []repositoryJSON{}
+	for _, repo := range config.Repositories {
+		if !git.ContainsAll(repo.Labels, labels) {
+			continue
+		}
+		_, err := os.Stat(repo.LocalPath)
+		result = append(result, repositoryJSON{
+			Name:   repo.Name,
+			Url:    repo.Url,
+			Path:   repo.Path,
+			Labels: repo.Labels,
+			Synced: err == nil,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *apiServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	commits, ok := s.queryCommits(w, r)
+	if !ok {
+		return
+	}
+
+	result := statsJSON{
+		Commits:      len(commits),
+		Repositories: []repositoryStatJSON{},
+		Authors:      git.AuthorStats(commits),
+	}
+	counts := make(map[string]int)
+	for _, c := range commits {
+		counts[c.Name]++
+	}
+	for name, count := range counts {
+		result.Repositories = append(result.Repositories, repositoryStatJSON{Name: name, Commits: count})
+	}
+	sort.Slice(result.Repositories, func(i, j int) bool {
+		return result.Repositories[i].Commits > result.Repositories[j].Commits
+	})
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *apiServer) handleAuthors(w http.ResponseWriter, r *http.Request) {
+	commits, ok := s.queryCommits(w, r)
+	if !ok {
+		return
+	}
+
+	result := []authorJSON{}
+	index := make(map[string]int)
+	for _, c := range commits {
+		i, ok := index[c.Commit.Author.Email]
+		if !ok {
+			i = len(result)
+			index[c.Commit.Author.Email] = i
+			result = append(result, authorJSON{Name: c.Commit.Author.Name, Email: c.Commit.Author.Email})
+		}
+		result[i].Commits++
+		// Commits are sorted by date
+		result[i].LastCommit = c.Commit.Author.When
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Commits > result[j].Commits
+	})
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *apiServer) handleDiff(w http.ResponseWriter, r *http.Request) {
+	repo, ok := findRepository(r.URL.Query().Get("repo"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "repository not found"})
+		return
+	}
+
+	defer s.locks.readLock([]git.Repository{repo})()
+
+	commit, err := repo.FindCommit(r.URL.Query().Get("hash"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	diff, err := commit.Diff()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, diff)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("%v\n", err)
+	}
+}
+
+func init() {
+	serveCmd.Flags().String("addr", ":8080", "address to listen on")
+	serveCmd.Flags().String("metrics-addr", "", "address to serve the prometheus metrics of the syncs on, under /metrics (e.g. :9100), disabled by default")
+	serveCmd.Flags().Duration("sync-interval", 0, "synchronizes git repositories in the background at the given interval (e.g. 15m), disabled by default")
+	rootCmd.AddCommand(serveCmd)
+}
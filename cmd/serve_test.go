@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"github.com/ttauveron/git-follow-up/git"
+	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// withRepositories sets the configured repositories, restored once the test ends
+func withRepositories(t *testing.T, repos []git.Repository) {
+	saved := config.Repositories
+	config.Repositories = repos
+	t.Cleanup(func() { config.Repositories = saved })
+}
+
+// initLocalRepo creates a local clone with a commit in a temp dir, returning its path and the commit hash
+func initLocalRepo(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	repo, err := gogit.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# repo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("README.md"); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := worktree.Commit("Initial commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Jean", Email: "jean@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, hash.String()
+}
+
+func TestApiServer(t *testing.T) {
+	dir, hash := initLocalRepo(t)
+	withRepositories(t, []git.Repository{
+		{Name: "local", Path: dir, LocalPath: dir, Labels: []string{"go", "cli"}},
+		{Name: "cobra", Url: "https://github.com/spf13/cobra.git", LocalPath: filepath.Join(dir, "missing"), Labels: []string{"go"}},
+		{Name: "react", Url: "https://github.com/facebook/react.git", LocalPath: filepath.Join(dir, "missing"), Labels: []string{"js"}},
+	})
+	server := httptest.NewServer((&apiServer{}).handler())
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "commits", url: "/api/commits?repo=local&from=2000-01-01", wantStatus: http.StatusOK},
+		{name: "unknown parameter", url: "/api/commits?since=2000-01-01", wantStatus: http.StatusBadRequest},
+		{name: "bad from", url: "/api/commits?from=lastweek", wantStatus: http.StatusBadRequest},
+		{name: "bad to", url: "/api/stats?to=someday", wantStatus: http.StatusBadRequest},
+		{name: "diff", url: "/api/diff?repo=local&hash=" + hash, wantStatus: http.StatusOK},
+		{name: "diff of an unknown repo", url: "/api/diff?repo=vue&hash=" + hash, wantStatus: http.StatusNotFound},
+		{name: "diff of an unknown hash", url: "/api/diff?repo=local&hash=0123456789012345678901234567890123456789", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("GET %v status = %v, want %v", tt.url, resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandleRepos(t *testing.T) {
+	dir, _ := initLocalRepo(t)
+	withRepositories(t, []git.Repository{
+		{Name: "local", Path: dir, LocalPath: dir, Labels: []string{"go", "cli"}},
+		{Name: "cobra", Url: "https://github.com/spf13/cobra.git", LocalPath: filepath.Join(dir, "missing"), Labels: []string{"go"}},
+		{Name: "react", Url: "https://github.com/facebook/react.git", LocalPath: filepath.Join(dir, "missing"), Labels: []string{"js"}},
+	})
+	server := httptest.NewServer((&apiServer{}).handler())
+	defer server.Close()
+
+	tests := []struct {
+		query     string
+		wantNames []string
+	}{
+		{query: "", wantNames: []string{"local", "cobra", "react"}},
+		{query: "?label=go", wantNames: []string{"local", "cobra"}},
+		{query: "?label=go&label=cli", wantNames: []string{"local"}},
+		{query: "?label=rust", wantNames: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/api/repos" + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var repos []repositoryJSON
+			if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, repo := range repos {
+				names = append(names, repo.Name)
+				if wantSynced := repo.Name == "local"; repo.Synced != wantSynced {
+					t.Errorf("repo %v synced = %v, want %v", repo.Name, repo.Synced, wantSynced)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("GET /api/repos%v = %v, want %v", tt.query, names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestRepoLocks(t *testing.T) {
+	var locks repoLocks
+	repos := []git.Repository{{Name: "cobra"}, {Name: "viper"}}
+	unlock := locks.readLock(repos)
+
+	// A sync of another repository isn't blocked by the readers
+	other := locks.get("pflag")
+	other.Lock()
+	other.Unlock()
+
+	synced := make(chan struct{})
+	go func() {
+		locks.get("viper").Lock()
+		close(synced)
+		locks.get("viper").Unlock()
+	}()
+	select {
+	case <-synced:
+		t.Fatalf("references of a read repository updated")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-synced
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/ttauveron/git-follow-up/git"
+	"os"
+	"text/tabwriter"
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Counts commits by author in your tracked repositories",
+	Long: `Counts commits by author in your tracked repositories
+Co-authors credited with a Co-authored-by trailer are counted separately.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		filter, err = git.NewFilter(cmd.Flags())
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return
+		}
+
+		// Sync repos if update flag is provided
+		doUpdate, err := cmd.Flags().GetBool("update")
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
+
+		if doUpdate {
+			updateCmd.Run(cmd, args)
+		}
+
+		stats := git.AuthorStats(listCommits(filterRepos(*filter), *filter))
+
+		// initialize tabwriter
+		w := new(tabwriter.Writer)
+		defer w.Flush()
+
+		// minwidth, tabwidth, padding, padchar, flags
+		w.Init(os.Stdout, 8, 8, 1, ' ', 0)
+		fmt.Fprintln(w, "AUTHOR\tCOMMITS\tCO-AUTHORED")
+		for _, stat := range stats {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", stat.Name, stat.Commits, stat.CoAuthored)
+		}
+	},
+}
+
+func init() {
+	addFilterFlags(statsCmd.Flags())
+	rootCmd.AddCommand(statsCmd)
+}
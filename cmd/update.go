@@ -16,8 +16,10 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/ttauveron/git-follow-up/followup"
 	"github.com/ttauveron/git-follow-up/git"
 	"sync"
 )
@@ -44,30 +46,91 @@ This operation may initially take some time for large repositories...
 			repoList = append(repoList, config.Repositories...)
 		}
 
+		textfile, _ := cmd.Flags().GetString("metrics-textfile")
+		if textfile != "" {
+			repoMetrics = newSyncMetrics(config.Repositories)
+		}
+
 		UpdateRepos(repoList)
+
+		if textfile != "" {
+			if err := repoMetrics.writeTextfile(textfile); err != nil {
+				fmt.Printf("%v\n", err)
+			}
+		}
 	},
 }
 
 func init() {
 	updateCmd.Flags().StringSlice("label", []string{}, "filters by project labels")
+	updateCmd.Flags().String("metrics-textfile", "", "file the prometheus metrics of the syncs are written to, for the textfile collector of the node exporter (e.g. /var/lib/node_exporter/git_follow_up.prom)")
 	rootCmd.AddCommand(updateCmd)
 
 }
 
 // Syncing all repositories defined in the `config.yaml` file
 func UpdateRepos(repos []git.Repository) {
-	var wg sync.WaitGroup
-	for _, repo := range repos {
+	updateRepos(repos, nil)
+}
 
-		wg.Add(1)
+// updateRepos syncs the repositories, their references being updated while holding their lock if locks isn't nil
+func updateRepos(repos []git.Repository, locks *repoLocks) {
+	results := syncRepos(repos, locks)
+	printSyncSummary(results)
+	notifyNewCommits(results)
+}
 
-		go func(repository git.Repository) {
-			err := repository.SyncRepo()
-			if err != nil {
-				fmt.Printf("%v\n", err)
+func printSyncSummary(results []followup.SyncResult) {
+	var failed []followup.SyncResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	fmt.Printf("%d/%d repositories synced\n", len(results)-len(failed), len(results))
+	for _, result := range failed {
+		fmt.Printf("\033[1;31mFailed\033[0m %v\n", result.Err)
+	}
+}
+
+// syncRepos syncs the repositories concurrently with the library client, each one being locked against the syncs
+// of other processes. Their references are updated while holding their lock if locks isn't nil, for readers of the same process
+func syncRepos(repos []git.Repository, locks *repoLocks) []followup.SyncResult {
+	opts := followup.Options{
+		Repositories: repos,
+		GitPath:      gitPath,
+		SSH:          config.SSH,
+		LockTimeout:  lockTimeout,
+		// Fetched commits are only listed when notifications are configured
+		NewCommits: len(config.Notifications) > 0,
+		OnWait: func(repo git.Repository, pid int) {
+			fmt.Printf("repo %v is being synced by PID %d, waiting up to %v...\n", repo.Name, pid, lockTimeout)
+		},
+		OnStaleLock: func(repo git.Repository, pid int) {
+			fmt.Printf("Removed the stale lock of repo %v, left by PID %d\n", repo.Name, pid)
+		},
+		OnSync: func(repo git.Repository) {
+			if !repo.IsLocal() {
+				fmt.Println("Syncing " + repo.Name + "...")
 			}
-			wg.Done()
-		}(repo)
+		},
+	}
+	if locks != nil {
+		opts.RefsLock = func(repo git.Repository) sync.Locker {
+			return locks.get(repo.Name)
+		}
+	}
+	client, err := followup.NewClient(opts)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return nil
+	}
+	results, err := client.Sync(context.Background(), nil)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+	}
+	if repoMetrics != nil {
+		repoMetrics.record(results)
 	}
-	wg.Wait()
+	return results
 }
@@ -0,0 +1,448 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/ttauveron/git-follow-up/git"
+	"gopkg.in/yaml.v3"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// configError is a config file error, located by line and column
+type configError struct {
+	file    string
+	line    int
+	column  int
+	path    string
+	message string
+}
+
+func (e configError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.file, e.line, e.column, e.path, e.message)
+}
+
+// Known keys of the config file, to detect misspelled ones
+var configKeys = []string{"daemon", "include", "notifications", "profiles", "repositories", "smtp", "ssh", "webhook"}
+var repositoryKeys = []string{"name", "url", "path", "labels", "authentication", "issue_trackers"}
+var hostKeyCheckingKeys = []string{"known_hosts", "host_key_fingerprint", "strict_host_key_checking"}
+var authenticationKeys = append([]string{"type", "auth_file", "passphrase", "token", "username"}, hostKeyCheckingKeys...)
+var issueTrackerKeys = []string{"pattern", "url"}
+var daemonKeys = []string{"interval", "cron", "state_file", "schedules"}
+var scheduleKeys = []string{"repos", "labels", "interval", "cron"}
+var webhookKeys = []string{"secret", "debounce"}
+var notificationKeys = []string{"name", "labels", "authors", "paths", "message", "targets"}
+var notificationTargetKeys = []string{"type", "url", "to", "subject", "template"}
+var smtpKeys = []string{"host", "port", "username", "password", "from"}
+
+// Booleans of yaml 1.1, read by viper
+var yamlBooleans = []string{"yes", "no", "on", "off", "true", "false", "y", "n"}
+
+type configValidator struct {
+	file   string
+	errors []error
+	// Location of the repository names, across files
+	names map[string]configError
+}
+
+func newConfigValidator() *configValidator {
+	return &configValidator{names: make(map[string]configError)}
+}
+
+func (v *configValidator) errorf(node *yaml.Node, path string, format string, args ...interface{}) {
+	v.errors = append(v.errors, configError{
+		file:    v.file,
+		line:    node.Line,
+		column:  node.Column,
+		path:    path,
+		message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validate checks the structure and values of a config file
+func (v *configValidator) validate(doc *configDocument) {
+	v.file = doc.path
+	root := doc.root.Content[0]
+	v.checkKeys(root, "config", configKeys)
+
+	if include := mappingValue(root, "include"); include != nil {
+		v.checkScalarList(include, "include")
+	}
+
+	if ssh := mappingValue(root, "ssh"); ssh != nil {
+		v.checkHostKeyChecking(ssh, "ssh", hostKeyCheckingKeys)
+	}
+
+	if profiles := mappingValue(root, "profiles"); profiles != nil {
+		v.checkProfiles(profiles)
+	}
+
+	if daemon := mappingValue(root, "daemon"); daemon != nil {
+		v.checkDaemon(daemon)
+	}
+
+	if notifications := mappingValue(root, "notifications"); notifications != nil {
+		v.checkNotifications(notifications)
+	}
+
+	if smtp := mappingValue(root, "smtp"); smtp != nil {
+		v.checkKeys(smtp, "smtp", smtpKeys)
+		if port := mappingValue(smtp, "port"); port != nil && smtp.Kind == yaml.MappingNode && port.Tag != "!!int" {
+			v.errorf(port, "smtp.port", "must be a number")
+		}
+	}
+
+	if webhook := mappingValue(root, "webhook"); webhook != nil {
+		v.checkKeys(webhook, "webhook", webhookKeys)
+		if debounce := mappingValue(webhook, "debounce"); debounce != nil && webhook.Kind == yaml.MappingNode {
+			if _, err := time.ParseDuration(debounce.Value); err != nil {
+				v.errorf(debounce, "webhook.debounce", "%v", err)
+			}
+		}
+	}
+
+	repos := mappingValue(root, "repositories")
+	if repos == nil {
+		return
+	}
+	if repos.Kind != yaml.SequenceNode {
+		v.errorf(repos, "repositories", "must be a list")
+		return
+	}
+
+	for i, repo := range repos.Content {
+		path := fmt.Sprintf("repositories[%d]", i)
+		if repo.Kind != yaml.MappingNode {
+			v.errorf(repo, path, "must be a mapping")
+			continue
+		}
+		v.checkKeys(repo, path, repositoryKeys)
+
+		name := mappingValue(repo, "name")
+		if name == nil {
+			v.errorf(repo, path, "name is required")
+		} else if err := git.ValidateName(name.Value); err != nil {
+			v.errorf(name, path+".name", "%v", err)
+		} else if first, ok := v.names[name.Value]; ok {
+			v.errorf(name, path+".name", "duplicate name %q (first defined in %s:%d)", name.Value, first.file, first.line)
+		} else {
+			v.names[name.Value] = configError{file: v.file, line: name.Line}
+		}
+
+		url := mappingValue(repo, "url")
+		localPath := mappingValue(repo, "path")
+		switch {
+		case url != nil && localPath != nil:
+			v.errorf(localPath, path+".path", "url and path are mutually exclusive")
+		case url != nil:
+			if err := git.ValidateUrl(url.Value); err != nil {
+				v.errorf(url, path+".url", "%v", err)
+			}
+		case localPath != nil:
+			if localPath.Value == "" {
+				v.errorf(localPath, path+".path", "path is empty")
+			}
+		default:
+			v.errorf(repo, path, "url or path is required")
+		}
+
+		if labels := mappingValue(repo, "labels"); labels != nil {
+			v.checkScalarList(labels, path+".labels")
+		}
+		if auth := mappingValue(repo, "authentication"); auth != nil {
+			v.checkAuthentication(auth, path+".authentication")
+		}
+		if trackers := mappingValue(repo, "issue_trackers"); trackers != nil {
+			v.checkIssueTrackers(trackers, path+".issue_trackers")
+		}
+	}
+}
+
+// checkKeys reports keys of a mapping which aren't known, suggesting the closest known key
+func (v *configValidator) checkKeys(mapping *yaml.Node, path string, known []string) {
+	if mapping.Kind != yaml.MappingNode {
+		v.errorf(mapping, path, "must be a mapping")
+		return
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i]
+		// Keys are case insensitive, as read by viper
+		if git.Contains(known, strings.ToLower(key.Value)) {
+			continue
+		}
+		if suggestion := closestKey(strings.ToLower(key.Value), known); suggestion != "" {
+			v.errorf(key, path, "unknown key %q, did you mean %q?", key.Value, suggestion)
+		} else {
+			v.errorf(key, path, "unknown key %q", key.Value)
+		}
+	}
+}
+
+func (v *configValidator) checkScalarList(list *yaml.Node, path string) {
+	if list.Kind != yaml.SequenceNode {
+		v.errorf(list, path, "must be a list")
+		return
+	}
+	for i, item := range list.Content {
+		if item.Kind != yaml.ScalarNode {
+			v.errorf(item, fmt.Sprintf("%s[%d]", path, i), "must be a string")
+		}
+	}
+}
+
+func (v *configValidator) checkAuthentication(auth *yaml.Node, path string) {
+	v.checkHostKeyChecking(auth, path, authenticationKeys)
+	if auth.Kind != yaml.MappingNode {
+		return
+	}
+
+	authType := mappingValue(auth, "type")
+	if authType == nil {
+		v.errorf(auth, path, "type is required")
+		return
+	}
+	if !git.Contains(git.AuthTypes, authType.Value) {
+		v.errorf(authType, path+".type", "unknown authentication type %q (%s)", authType.Value, strings.Join(git.AuthTypes, ", "))
+	}
+	if authType.Value == "access_token" && mappingValue(auth, "auth_file") == nil && mappingValue(auth, "token") == nil {
+		v.errorf(auth, path, "auth_file or token is required for an access_token authentication")
+	}
+}
+
+func (v *configValidator) checkHostKeyChecking(node *yaml.Node, path string, known []string) {
+	v.checkKeys(node, path, known)
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	strict := mappingValue(node, "strict_host_key_checking")
+	if strict != nil && strict.Tag != "!!bool" && !git.Contains(yamlBooleans, strings.ToLower(strict.Value)) {
+		v.errorf(strict, path+".strict_host_key_checking", "must be a boolean (yes, no)")
+	}
+}
+
+func (v *configValidator) checkIssueTrackers(trackers *yaml.Node, path string) {
+	if trackers.Kind != yaml.SequenceNode {
+		v.errorf(trackers, path, "must be a list")
+		return
+	}
+	for i, tracker := range trackers.Content {
+		trackerPath := fmt.Sprintf("%s[%d]", path, i)
+		v.checkKeys(tracker, trackerPath, issueTrackerKeys)
+		if tracker.Kind != yaml.MappingNode {
+			continue
+		}
+		pattern := mappingValue(tracker, "pattern")
+		if pattern == nil {
+			v.errorf(tracker, trackerPath, "pattern is required")
+		} else if _, err := regexp.Compile(pattern.Value); err != nil {
+			v.errorf(pattern, trackerPath+".pattern", "%v", err)
+		}
+	}
+}
+
+// checkProfiles checks that profiles map flag names to a value or a list of values
+func (v *configValidator) checkProfiles(profiles *yaml.Node) {
+	if profiles.Kind != yaml.MappingNode {
+		v.errorf(profiles, "profiles", "must be a mapping")
+		return
+	}
+	for i := 0; i+1 < len(profiles.Content); i += 2 {
+		path := "profiles." + profiles.Content[i].Value
+		profile := profiles.Content[i+1]
+		if profile.Kind != yaml.MappingNode {
+			v.errorf(profile, path, "must be a mapping of flag names to values")
+			continue
+		}
+		for j := 0; j+1 < len(profile.Content); j += 2 {
+			value := profile.Content[j+1]
+			if value.Kind == yaml.SequenceNode {
+				v.checkScalarList(value, path+"."+profile.Content[j].Value)
+			} else if value.Kind != yaml.ScalarNode {
+				v.errorf(value, path+"."+profile.Content[j].Value, "must be a value or a list of values")
+			}
+		}
+	}
+}
+
+// checkDaemon checks the default schedule and the schedules of the daemon
+func (v *configValidator) checkDaemon(daemon *yaml.Node) {
+	v.checkKeys(daemon, "daemon", daemonKeys)
+	if daemon.Kind != yaml.MappingNode {
+		return
+	}
+	if mappingValue(daemon, "interval") != nil || mappingValue(daemon, "cron") != nil {
+		v.checkSchedule(daemon, "daemon")
+	}
+
+	schedules := mappingValue(daemon, "schedules")
+	if schedules == nil {
+		return
+	}
+	if schedules.Kind != yaml.SequenceNode {
+		v.errorf(schedules, "daemon.schedules", "must be a list")
+		return
+	}
+	for i, schedule := range schedules.Content {
+		path := fmt.Sprintf("daemon.schedules[%d]", i)
+		v.checkKeys(schedule, path, scheduleKeys)
+		if schedule.Kind != yaml.MappingNode {
+			continue
+		}
+		for _, key := range []string{"repos", "labels"} {
+			if list := mappingValue(schedule, key); list != nil {
+				v.checkScalarList(list, path+"."+key)
+			}
+		}
+		v.checkSchedule(schedule, path)
+	}
+}
+
+// checkSchedule checks the interval or cron expression of a schedule
+func (v *configValidator) checkSchedule(node *yaml.Node, path string) {
+	var schedule git.Schedule
+	location := node
+	if interval := mappingValue(node, "interval"); interval != nil {
+		schedule.Interval = interval.Value
+		location = interval
+	}
+	if cron := mappingValue(node, "cron"); cron != nil {
+		schedule.Cron = cron.Value
+		location = cron
+	}
+	if err := schedule.Validate(); err != nil {
+		v.errorf(location, path, "%v", err)
+	}
+}
+
+// checkNotifications checks the rules and targets of the notifications
+func (v *configValidator) checkNotifications(notifications *yaml.Node) {
+	if notifications.Kind != yaml.SequenceNode {
+		v.errorf(notifications, "notifications", "must be a list")
+		return
+	}
+	for i, notification := range notifications.Content {
+		path := fmt.Sprintf("notifications[%d]", i)
+		v.checkKeys(notification, path, notificationKeys)
+		if notification.Kind != yaml.MappingNode {
+			continue
+		}
+		if mappingValue(notification, "name") == nil {
+			v.errorf(notification, path, "name is required")
+		}
+		for _, key := range []string{"labels", "authors", "paths"} {
+			if list := mappingValue(notification, key); list != nil {
+				v.checkScalarList(list, path+"."+key)
+			}
+		}
+		if message := mappingValue(notification, "message"); message != nil {
+			if _, err := regexp.Compile(message.Value); err != nil {
+				v.errorf(message, path+".message", "%v", err)
+			}
+		}
+		if paths := mappingValue(notification, "paths"); paths != nil && paths.Kind == yaml.SequenceNode {
+			for j, pattern := range paths.Content {
+				if _, err := filepath.Match(pattern.Value, ""); err != nil {
+					v.errorf(pattern, fmt.Sprintf("%s.paths[%d]", path, j), "%v", err)
+				}
+			}
+		}
+
+		targets := mappingValue(notification, "targets")
+		if targets == nil || targets.Kind != yaml.SequenceNode || len(targets.Content) == 0 {
+			v.errorf(notification, path, "targets must be a list of at least one target")
+			continue
+		}
+		for j, target := range targets.Content {
+			v.checkNotificationTarget(target, fmt.Sprintf("%s.targets[%d]", path, j))
+		}
+	}
+}
+
+func (v *configValidator) checkNotificationTarget(target *yaml.Node, path string) {
+	v.checkKeys(target, path, notificationTargetKeys)
+	if target.Kind != yaml.MappingNode {
+		return
+	}
+	targetType := mappingValue(target, "type")
+	if targetType == nil {
+		v.errorf(target, path, "type is required")
+		return
+	}
+	if !git.Contains(git.NotificationTypes, targetType.Value) {
+		v.errorf(targetType, path+".type", "unknown notification type %q (%s)", targetType.Value, strings.Join(git.NotificationTypes, ", "))
+		return
+	}
+	if targetType.Value == "email" {
+		to := mappingValue(target, "to")
+		if to == nil {
+			v.errorf(target, path, "to is required for an email target")
+		} else {
+			v.checkScalarList(to, path+".to")
+		}
+	} else if mappingValue(target, "url") == nil {
+		v.errorf(target, path, "url is required for a %v target", targetType.Value)
+	}
+	for _, key := range []string{"subject", "template"} {
+		if text := mappingValue(target, key); text != nil {
+			if _, err := template.New(key).Parse(text.Value); err != nil {
+				v.errorf(text, path+"."+key, "%v", err)
+			}
+		}
+	}
+}
+
+// closestKey returns the known key within an edit distance of 2, if any
+func closestKey(key string, known []string) (closest string) {
+	best := 3
+	for _, k := range known {
+		if d := editDistance(key, k); d < best {
+			best = d
+			closest = k
+		}
+	}
+	return closest
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
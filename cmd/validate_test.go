@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// Validated files, the first one being the main file
+		paths []string
+		want  []string
+	}{
+		{
+			name: "valid",
+			files: map[string]string{"config.yaml": `
+repositories:
+  - name: cobra
+    url: https://github.com/spf13/cobra.git
+    labels: [go]
+`},
+			want: nil,
+		},
+		{
+			name: "case insensitive keys",
+			files: map[string]string{"config.yaml": `
+Repositories:
+  - Name: cobra
+    URL: https://github.com/spf13/cobra.git
+    Authentication:
+      Type: ssh
+      Strict_Host_Key_Checking: yes
+`},
+			want: nil,
+		},
+		{
+			name: "unknown keys with suggestions",
+			files: map[string]string{"config.yaml": `
+repositorys: []
+colors: true
+`},
+			want: []string{
+				`config.yaml:2:1: config: unknown key "repositorys", did you mean "repositories"?`,
+				`config.yaml:3:1: config: unknown key "colors"`,
+			},
+		},
+		{
+			name: "unknown repository key",
+			files: map[string]string{"config.yaml": `
+repositories:
+  - name: cobra
+    url: https://github.com/spf13/cobra.git
+    label: [go]
+`},
+			want: []string{`config.yaml:5:5: repositories[0]: unknown key "label", did you mean "labels"?`},
+		},
+		{
+			name: "wrong types",
+			files: map[string]string{"config.yaml": `
+include: other.yaml
+smtp:
+  host: smtp.example.com
+  port: twenty-five
+repositories:
+  - name: cobra
+    url: https://github.com/spf13/cobra.git
+    labels: go
+  - cobra
+`},
+			want: []string{
+				`config.yaml:2:10: include: must be a list`,
+				`config.yaml:5:9: smtp.port: must be a number`,
+				`config.yaml:9:13: repositories[0].labels: must be a list`,
+				`config.yaml:10:5: repositories[1]: must be a mapping`,
+			},
+		},
+		{
+			name: "repositories not a list",
+			files: map[string]string{"config.yaml": `
+repositories: cobra
+`},
+			want: []string{`config.yaml:2:15: repositories: must be a list`},
+		},
+		{
+			name: "invalid names and urls",
+			files: map[string]string{"config.yaml": `
+repositories:
+  - name: spf13/cobra
+    url: https://github.com/spf13/cobra.git
+  - name: viper
+    url: ftp://github.com/spf13/viper.git
+  - name: pflag
+`},
+			want: []string{
+				`config.yaml:3:11: repositories[0].name: invalid name "spf13/cobra" : only letters, digits, '.', '_' and '-' are allowed`,
+				`config.yaml:6:10: repositories[1].url: invalid url "ftp://github.com/spf13/viper.git" : unsupported scheme ftp`,
+				`config.yaml:7:5: repositories[2]: url or path is required`,
+			},
+		},
+		{
+			name: "duplicate names across included files",
+			files: map[string]string{
+				"config.yaml": `
+repositories:
+  - name: cobra
+    url: https://github.com/spf13/cobra.git
+`,
+				"team.yaml": `
+repositories:
+  - name: viper
+    url: https://github.com/spf13/viper.git
+  - name: cobra
+    url: https://gitlab.com/spf13/cobra.git
+`,
+			},
+			paths: []string{"config.yaml", "team.yaml"},
+			want:  []string{`team.yaml:5:11: repositories[1].name: duplicate name "cobra" (first defined in config.yaml:3)`},
+		},
+		{
+			name: "bad authentication",
+			files: map[string]string{"config.yaml": `
+repositories:
+  - name: cobra
+    url: https://github.com/spf13/cobra.git
+    authentication:
+      type: password
+  - name: viper
+    url: https://github.com/spf13/viper.git
+    authentication:
+      type: access_token
+  - name: pflag
+    url: git@github.com:spf13/pflag.git
+    authentication:
+      auth_file: ~/.ssh/id_ed25519
+`},
+			want: []string{
+				`config.yaml:6:13: repositories[0].authentication.type: unknown authentication type "password" (ssh, ssh_agent, access_token)`,
+				`config.yaml:10:7: repositories[1].authentication: auth_file or token is required for an access_token authentication`,
+				`config.yaml:14:7: repositories[2].authentication: type is required`,
+			},
+		},
+		{
+			name: "bad host key checking",
+			files: map[string]string{"config.yaml": `
+ssh:
+  strict_host_key_checking: maybe
+  known_host: ~/.ssh/known_hosts
+repositories:
+  - name: cobra
+    url: git@github.com:spf13/cobra.git
+    authentication:
+      type: ssh
+      strict_host_key_checking: sometimes
+`},
+			want: []string{
+				`config.yaml:4:3: ssh: unknown key "known_host", did you mean "known_hosts"?`,
+				`config.yaml:3:29: ssh.strict_host_key_checking: must be a boolean (yes, no)`,
+				`config.yaml:10:33: repositories[0].authentication.strict_host_key_checking: must be a boolean (yes, no)`,
+			},
+		},
+		{
+			name:  "non yaml files are skipped",
+			files: map[string]string{"config.toml": "[[repositories]]\nname = \"cobra\"\n"},
+			paths: []string{"config.toml"},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := withConfigFiles(t, tt.files)
+			paths := tt.paths
+			if paths == nil {
+				paths = []string{"config.yaml"}
+			}
+			for i := range paths {
+				paths[i] = filepath.Join(dir, paths[i])
+			}
+
+			errors, err := validateConfigFiles(paths)
+			if err != nil {
+				t.Fatalf("validateConfigFiles() error = %v", err)
+			}
+			var got []string
+			for _, err := range errors {
+				got = append(got, strings.Replace(err.Error(), dir+string(filepath.Separator), "", -1))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateConfigFiles() =\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+"use strict";
+
+// Filters applied to the api, reflected in the url hash so that views can be shared
+const state = {
+  label: "",
+  repo: "",
+  author: "",
+  from: "",
+  to: ""
+};
+
+const $ = (id) => document.getElementById(id);
+
+function isoDate(date) {
+  return date.toISOString().slice(0, 10);
+}
+
+function defaultFrom() {
+  const date = new Date();
+  date.setMonth(date.getMonth() - 3);
+  return isoDate(date);
+}
+
+function query() {
+  const params = new URLSearchParams();
+  params.set("from", state.from || defaultFrom());
+  if (state.to) params.set("to", state.to);
+  if (state.label) params.set("label", state.label);
+  if (state.repo) params.set("repo", state.repo);
+  if (state.author) params.set("author", state.author);
+  return params;
+}
+
+async function fetchJSON(url) {
+  const response = await fetch(url);
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+function saveState() {
+  const params = new URLSearchParams();
+  for (const [key, value] of Object.entries(state)) {
+    if (value) params.set(key, value);
+  }
+  history.replaceState(null, "", "#" + params.toString());
+}
+
+function loadState() {
+  const params = new URLSearchParams(location.hash.slice(1));
+  for (const key of Object.keys(state)) {
+    state[key] = params.get(key) || "";
+  }
+  $("author").value = state.author;
+  $("from").value = state.from;
+  $("to").value = state.to;
+}
+
+function listItem(text, selected, onClick) {
+  const li = document.createElement("li");
+  li.textContent = text;
+  li.classList.toggle("selected", selected);
+  li.addEventListener("click", onClick);
+  return li;
+}
+
+async function renderSidebar() {
+  const repos = await fetchJSON("/api/repos");
+  const labels = [...new Set(repos.flatMap((r) => r.labels || []))].sort();
+
+  const labelList = $("labels");
+  labelList.replaceChildren(...labels.map((label) =>
+    listItem(label, state.label === label, () => {
+      state.label = state.label === label ? "" : label;
+      refresh();
+    })));
+
+  const repoList = $("repos");
+  repoList.replaceChildren(...repos
+    .filter((r) => !state.label || (r.labels || []).includes(state.label))
+    .map((r) => listItem(r.name + (r.synced ? "" : " (not synced)"), state.repo === r.name, () => {
+      state.repo = state.repo === r.name ? "" : r.name;
+      refresh();
+    })));
+}
+
+function renderHeatmap(commits) {
+  const counts = {};
+  for (const c of commits) {
+    const day = c.date.slice(0, 10);
+    counts[day] = (counts[day] || 0) + 1;
+  }
+  const max = Math.max(1, ...Object.values(counts));
+
+  const start = new Date(state.from || defaultFrom());
+  // Columns start on mondays
+  start.setDate(start.getDate() - ((start.getDay() + 6) % 7));
+  const end = state.to ? new Date(state.to) : new Date();
+
+  const days = [];
+  for (const d = new Date(start); d <= end; d.setDate(d.getDate() + 1)) {
+    const day = isoDate(d);
+    const count = counts[day] || 0;
+    const cell = document.createElement("div");
+    cell.className = "day";
+    if (count > 0) {
+      cell.classList.add("level-" + Math.ceil((count / max) * 4));
+    }
+    cell.title = day + " : " + count + " commit" + (count === 1 ? "" : "s");
+    days.push(cell);
+  }
+  $("heatmap").replaceChildren(...days);
+  $("total").textContent = "(" + commits.length + " commits)";
+}
+
+function renderTimeline(commits) {
+  const timeline = $("timeline");
+  const nodes = [];
+  let currentDay = "";
+  // Most recent first
+  for (const c of [...commits].reverse()) {
+    const day = c.date.slice(0, 10);
+    if (day !== currentDay) {
+      currentDay = day;
+      const title = document.createElement("h3");
+      title.textContent = day;
+      nodes.push(title);
+    }
+
+    const row = document.createElement("div");
+    row.className = "commit";
+    for (const [className, text] of [
+      ["repo", c.repository],
+      ["hash", c.hash.slice(0, 8)],
+      ["subject", c.subject],
+      ["author", [c.author, ...(c.co_authors || [])].join(", ")]
+    ]) {
+      const span = document.createElement("span");
+      span.className = className;
+      span.textContent = text;
+      row.appendChild(span);
+    }
+    row.title = c.message;
+    row.addEventListener("click", () => {
+      for (const selected of timeline.querySelectorAll(".commit.selected")) {
+        selected.classList.remove("selected");
+      }
+      row.classList.add("selected");
+      showDiff(c);
+    });
+    nodes.push(row);
+  }
+  if (nodes.length === 0) {
+    const empty = document.createElement("p");
+    empty.textContent = "No commits";
+    nodes.push(empty);
+  }
+  timeline.replaceChildren(...nodes);
+}
+
+async function showDiff(c) {
+  $("diff").hidden = false;
+  $("diff-title").textContent = c.repository + " " + c.hash.slice(0, 8) + " " + c.subject;
+  const content = $("diff-content");
+  content.textContent = "Loading...";
+
+  const params = new URLSearchParams({repo: c.repository, hash: c.hash});
+  const response = await fetch("/api/diff?" + params.toString());
+  const text = await response.text();
+  if (!response.ok) {
+    content.textContent = text;
+    return;
+  }
+
+  content.replaceChildren(...text.split("\n").map((line) => {
+    const span = document.createElement("span");
+    if (line.startsWith("diff --git") || line.startsWith("+++") || line.startsWith("---")) {
+      span.className = "file";
+    } else if (line.startsWith("+")) {
+      span.className = "add";
+    } else if (line.startsWith("-")) {
+      span.className = "del";
+    } else if (line.startsWith("@@")) {
+      span.className = "hunk";
+    }
+    span.textContent = line + "\n";
+    return span;
+  }));
+}
+
+async function refresh() {
+  saveState();
+  try {
+    await renderSidebar();
+    const commits = await fetchJSON("/api/commits?" + query().toString());
+    renderHeatmap(commits);
+    renderTimeline(commits);
+  } catch (err) {
+    $("timeline").textContent = "Error : " + err.message;
+  }
+}
+
+function init() {
+  loadState();
+
+  let timer;
+  $("author").addEventListener("input", () => {
+    clearTimeout(timer);
+    timer = setTimeout(() => {
+      state.author = $("author").value.trim().toLowerCase();
+      refresh();
+    }, 300);
+  });
+  for (const id of ["from", "to"]) {
+    $(id).addEventListener("change", () => {
+      state[id] = $(id).value;
+      refresh();
+    });
+  }
+  $("reset").addEventListener("click", () => {
+    for (const key of Object.keys(state)) {
+      state[key] = "";
+    }
+    loadState();
+    saveState();
+    refresh();
+  });
+  $("diff-close").addEventListener("click", () => {
+    $("diff").hidden = true;
+  });
+
+  refresh();
+}
+
+init();
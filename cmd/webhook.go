@@ -0,0 +1,157 @@
+/*
+Copyright © 2019 Thibaut Tauveron <thibaut.tauveron@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"github.com/ttauveron/git-follow-up/git"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultWebhookDebounce = "10s"
+
+// WebhookConfig holds the settings of the webhook receiver of the serve and daemon commands
+type WebhookConfig struct {
+	// Source of the shared secret : file:<path>, env:<VAR> or cmd:<command>
+	Secret string
+	// Delay before syncing a pushed repository, pushes within the delay triggering a single sync
+	Debounce string
+}
+
+// withDefaults merges the settings of another config file, the current settings taking precedence
+func (c WebhookConfig) withDefaults(defaults WebhookConfig) WebhookConfig {
+	if c.Secret == "" {
+		c.Secret = defaults.Secret
+	}
+	if c.Debounce == "" {
+		c.Debounce = defaults.Debounce
+	}
+	return c
+}
+
+// webhookReceiver syncs the repositories notified by push webhooks, once no push was received for the debounce delay
+type webhookReceiver struct {
+	webhook  *git.Webhook
+	debounce time.Duration
+	sync     func(repos []git.Repository)
+
+	mutex   sync.Mutex
+	pending map[string]*time.Timer
+	syncing sync.WaitGroup
+	stopped bool
+}
+
+func newWebhookReceiver(webhookConfig WebhookConfig, sync func(repos []git.Repository)) (*webhookReceiver, error) {
+	if webhookConfig.Secret == "" {
+		return nil, fmt.Errorf("webhook secret is required, set by the webhook section of the config file")
+	}
+	webhook, err := git.NewWebhook(webhookConfig.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if webhookConfig.Debounce == "" {
+		webhookConfig.Debounce = defaultWebhookDebounce
+	}
+	debounce, err := time.ParseDuration(webhookConfig.Debounce)
+	if err != nil {
+		return nil, fmt.Errorf("webhook debounce: %v", err)
+	}
+	return &webhookReceiver{
+		webhook:  webhook,
+		debounce: debounce,
+		sync:     sync,
+		pending:  make(map[string]*time.Timer),
+	}, nil
+}
+
+func (h *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST expected"})
+		return
+	}
+
+	event, err := h.webhook.Parse(r)
+	switch {
+	case err == git.ErrWebhookUnauthorized:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	case event == nil:
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "ignored"})
+		return
+	}
+
+	names := []string{}
+	for _, repo := range config.Repositories {
+		if event.Matches(repo) {
+			h.schedule(repo)
+			names = append(names, repo.Name)
+		}
+	}
+	if len(names) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("no tracked repository matches %v", event.Urls[0])})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string][]string{"repositories": names})
+}
+
+// schedule syncs the repository after the debounce delay, postponing its pending sync if any
+func (h *webhookReceiver) schedule(repo git.Repository) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.stopped {
+		return
+	}
+	if timer, ok := h.pending[repo.Name]; ok && timer.Stop() {
+		timer.Reset(h.debounce)
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(h.debounce, func() {
+		h.mutex.Lock()
+		if h.pending[repo.Name] == timer {
+			delete(h.pending, repo.Name)
+		}
+		// Fired while stopping, after stop stopped the pending timers
+		if h.stopped {
+			h.mutex.Unlock()
+			return
+		}
+		h.syncing.Add(1)
+		h.mutex.Unlock()
+
+		defer h.syncing.Done()
+		h.sync([]git.Repository{repo})
+	})
+	h.pending[repo.Name] = timer
+}
+
+// stop cancels the pending syncs, and waits for the running ones
+func (h *webhookReceiver) stop() {
+	h.mutex.Lock()
+	h.stopped = true
+	for name, timer := range h.pending {
+		timer.Stop()
+		delete(h.pending, name)
+	}
+	h.mutex.Unlock()
+	h.syncing.Wait()
+}
@@ -0,0 +1,322 @@
+// Package followup is the library api of git-follow-up, for programs embedding it instead of running the command line.
+//
+// A Client syncs the mirrors of the tracked repositories and queries their commits,
+// errors being returned instead of printed :
+//
+//	client, err := followup.NewClient(followup.Options{Repositories: repos})
+//	results, err := client.Sync(ctx, nil)
+//	commits, err := client.Commits(ctx, followup.Query{From: from, Labels: []string{"go"}})
+//	err = client.WriteCommits(ctx, os.Stdout, followup.Query{From: from, Display: []string{"hash", "message"}})
+package followup
+
+import (
+	"context"
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"github.com/ttauveron/git-follow-up/git"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultLockTimeout is how long a sync waits for another process syncing the same repository, when not set
+const DefaultLockTimeout = 5 * time.Minute
+
+// Options configures a Client, as the config file configures the command line
+type Options struct {
+	Repositories []git.Repository
+	// Directory the mirrors of the remote repositories are cloned into, shared with the command line by default
+	GitPath string
+	// Default ssh host key checking of the repositories
+	SSH git.HostKeyChecking
+	// How long a sync waits for another process syncing the same repository, DefaultLockTimeout if zero
+	LockTimeout time.Duration
+	// Whether syncs list the commits they fetch, in the NewCommits of their result
+	NewCommits bool
+	// Returns the lock held while a sync updates the references of a repository, for readers of the same process
+	// to read them consistently. References are updated without lock if nil.
+	RefsLock func(repo git.Repository) sync.Locker
+
+	// Optional callbacks following the syncs, called concurrently for different repositories
+	// OnWait is called when a sync waits for the process of PID pid syncing the same repository
+	OnWait func(repo git.Repository, pid int)
+	// OnStaleLock is called when the lock of a crashed process of PID pid is replaced
+	OnStaleLock func(repo git.Repository, pid int)
+	// OnSync is called when the sync of a repository starts, once it is locked
+	OnSync func(repo git.Repository)
+}
+
+// Client syncs and queries the tracked repositories
+type Client struct {
+	opts  Options
+	repos []git.Repository
+}
+
+// NewClient returns a client of the repositories of the options, whose names have to be unique
+func NewClient(opts Options) (*Client, error) {
+	gitPath := opts.GitPath
+	if gitPath == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil, err
+		}
+		gitPath = filepath.Join(home, ".git-follow-up", "git")
+	}
+
+	if opts.LockTimeout == 0 {
+		opts.LockTimeout = DefaultLockTimeout
+	}
+	c := &Client{opts: opts}
+
+	names := make(map[string]bool)
+	for _, repo := range opts.Repositories {
+		if repo.Name == "" {
+			return nil, fmt.Errorf("repository %v : missing name", repo.Url)
+		}
+		if names[repo.Name] {
+			return nil, fmt.Errorf("repository %v : duplicate name", repo.Name)
+		}
+		names[repo.Name] = true
+
+		// Repositories already resolved, as the ones of the config of the command line, keep their local copy
+		if repo.LocalPath == "" {
+			localPath, err := repo.ResolveLocalPath(gitPath)
+			if err != nil {
+				return nil, fmt.Errorf("%v : %v", repo.Name, err)
+			}
+			repo.LocalPath = localPath
+		}
+		repo.Authentication.HostKeyChecking = repo.Authentication.HostKeyChecking.WithDefaults(opts.SSH)
+		c.repos = append(c.repos, repo)
+	}
+	return c, nil
+}
+
+// Repositories returns the repositories of the client, with the path of their local copy
+func (c *Client) Repositories() []git.Repository {
+	return append([]git.Repository(nil), c.repos...)
+}
+
+// SyncResult is the outcome of the sync of a repository
+type SyncResult struct {
+	Repository git.Repository
+	// Start of the sync, once the repository is locked
+	Started  time.Time
+	Duration time.Duration
+	// Number of git objects fetched
+	Fetched int
+	// Commits fetched by the sync, sorted by date, when listed by the options
+	// Local clones and repositories cloned by the sync have none
+	NewCommits []git.Commit
+	// Error of the sync, nil if it succeeded
+	Err error
+}
+
+// Sync syncs the repositories named repos concurrently, all of them if repos is empty.
+// Each mirror is locked against the syncs of other processes while synced.
+// Failed syncs are reported by the Err of their result, the error being returned for unknown repositories
+// or once ctx is done.
+func (c *Client) Sync(ctx context.Context, repos []string) ([]SyncResult, error) {
+	selected, err := c.named(repos)
+	if err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	results := make([]SyncResult, len(selected))
+	for i, repo := range selected {
+
+		wg.Add(1)
+
+		go func(i int, repository git.Repository) {
+			results[i] = c.sync(ctx, repository)
+			wg.Done()
+		}(i, repo)
+	}
+	wg.Wait()
+	return results, ctx.Err()
+}
+
+func (c *Client) sync(ctx context.Context, repository git.Repository) SyncResult {
+	lock, err := repository.LockSync(ctx, c.opts.LockTimeout, func(pid int) {
+		if c.opts.OnWait != nil {
+			c.opts.OnWait(repository, pid)
+		}
+	})
+	result := SyncResult{Repository: repository, Started: time.Now()}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if lock != nil && lock.StalePID != 0 && c.opts.OnStaleLock != nil {
+		c.opts.OnStaleLock(repository, lock.StalePID)
+	}
+	if c.opts.OnSync != nil {
+		c.opts.OnSync(repository)
+	}
+
+	// Branch heads before the sync, to list the fetched commits
+	var heads map[string]plumbing.Hash
+	if c.opts.NewCommits && !repository.IsLocal() {
+		heads, err = repository.Heads()
+	}
+	var refsLock sync.Locker
+	if c.opts.RefsLock != nil {
+		refsLock = c.opts.RefsLock(repository)
+	}
+	if err == nil {
+		result.Fetched, err = repository.SyncRepoContext(ctx, refsLock)
+	}
+	// Listed before releasing the lock, while no other sync can move the branches
+	if err == nil && heads != nil {
+		result.NewCommits, err = repository.NewCommits(heads)
+	}
+	_ = lock.Release()
+	result.Duration = time.Since(result.Started)
+	result.Err = err
+	return result
+}
+
+// Query selects commits, as the flags of the commits command. Zero values don't filter.
+type Query struct {
+	// Start date
+	From time.Time
+	// Exclusive end date
+	To time.Time
+	// Names of the repositories
+	Repositories []string
+	// Labels the repositories all have
+	Labels []string
+	// Authors matched partially on their name and email
+	Authors []string
+	// Whether the author filter also matches Co-authored-by trailers
+	CoAuthors bool
+	// Trailers all found in the commit message, keys being case insensitive and values matched partially
+	Trailers []git.Trailer
+	// Issues one of which is referenced by the commit message (PROJ-123, #456)
+	Issues []string
+	// Collapses the commits found in several repositories or branches, by hash or by patch-id
+	Dedup string
+
+	// Output options of WriteCommits, as the display and output flags of the commits command
+	// Fields of the commits written as text, all of them if empty
+	Display []string
+	// Format the commits are written in, text or atom, text if empty
+	Output string
+}
+
+// filter returns the commit filter of the query
+func (q Query) filter() git.Filter {
+	return git.Filter{
+		From:      q.From,
+		To:        q.To,
+		Labels:    q.Labels,
+		Authors:   q.Authors,
+		Issues:    q.Issues,
+		CoAuthors: q.CoAuthors,
+		Trailers:  q.Trailers,
+		Display:   q.Display,
+	}
+}
+
+// Commits returns the commits of the local copies matching the query, sorted by date
+func (c *Client) Commits(ctx context.Context, q Query) ([]git.Commit, error) {
+	if q.Dedup != "" && !git.Contains(git.DedupArgs, q.Dedup) {
+		return nil, fmt.Errorf("dedup %q not recognized, possible values : %s", q.Dedup, strings.Join(git.DedupArgs, ", "))
+	}
+	repos, err := c.named(q.Repositories)
+	if err != nil {
+		return nil, err
+	}
+
+	f := q.filter()
+	var commits []git.Commit
+	for _, repo := range repos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Skip repos with non-matching labels
+		if !git.ContainsAll(repo.Labels, f.Labels) {
+			continue
+		}
+		cs, err := repo.ListCommits(f)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, cs...)
+	}
+	sort.Sort(git.ByDate(commits))
+
+	if q.Dedup != "" {
+		return git.Dedup(commits, q.Dedup == "patch-id")
+	}
+	return commits, nil
+}
+
+// WriteCommits writes the commits matching the query to w, in the output format of the query
+func (c *Client) WriteCommits(ctx context.Context, w io.Writer, q Query) error {
+	output := q.Output
+	if output == "" {
+		output = "text"
+	}
+	if !git.Contains(git.OutputArgs, output) {
+		return fmt.Errorf("output %q not recognized, possible values : %s", q.Output, strings.Join(git.OutputArgs, ", "))
+	}
+	display := q.Display
+	if len(display) == 0 {
+		display = git.DisplayArgs
+	}
+	for _, field := range display {
+		if !git.Contains(git.DisplayArgs, field) {
+			return fmt.Errorf("display %q not recognized, possible values : %s", field, strings.Join(git.DisplayArgs, ", "))
+		}
+	}
+
+	commits, err := c.Commits(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	if output == "atom" {
+		content, err := git.NewFeed(q.filter(), "", commits).Xml()
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}
+
+	// minwidth, tabwidth, padding, padchar, flags
+	tw := tabwriter.NewWriter(w, 8, 8, 0, ' ', 0)
+	for _, commit := range commits {
+		fmt.Fprintln(tw, commit.Format(display))
+	}
+	return tw.Flush()
+}
+
+// named returns the repositories named names, all of them if names is empty
+func (c *Client) named(names []string) ([]git.Repository, error) {
+	if len(names) == 0 {
+		return c.repos, nil
+	}
+	var repos []git.Repository
+	for _, name := range names {
+		found := false
+		for _, repo := range c.repos {
+			if repo.Name == name {
+				repos = append(repos, repo)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown repository %v", name)
+		}
+	}
+	return repos, nil
+}
@@ -0,0 +1,263 @@
+package followup
+
+import (
+	"bytes"
+	"context"
+	"github.com/ttauveron/git-follow-up/git"
+	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name      string
+		repos     []git.Repository
+		wantPaths []string
+		wantErr   bool
+	}{
+		{
+			name: "mirrors and local clones",
+			repos: []git.Repository{
+				{Name: "cobra", Url: "https://github.com/spf13/cobra.git"},
+				{Name: "local", Path: "/src/local"},
+				{Name: "file", Url: "file:///src/file"},
+			},
+			wantPaths: []string{filepath.Join("/var/git", "cobra"), "/src/local", "/src/file"},
+		},
+		{
+			name:    "missing name",
+			repos:   []git.Repository{{Url: "https://github.com/spf13/cobra.git"}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate name",
+			repos: []git.Repository{
+				{Name: "cobra", Url: "https://github.com/spf13/cobra.git"},
+				{Name: "cobra", Url: "https://gitlab.com/spf13/cobra.git"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(Options{Repositories: tt.repos, GitPath: "/var/git"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			repos := client.Repositories()
+			if len(repos) != len(tt.wantPaths) {
+				t.Fatalf("Repositories() = %v, want %d repositories", repos, len(tt.wantPaths))
+			}
+			for i, repo := range repos {
+				if repo.LocalPath != tt.wantPaths[i] {
+					t.Errorf("LocalPath of %v = %v, want %v", repo.Name, repo.LocalPath, tt.wantPaths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	client, err := NewClient(Options{
+		Repositories: []git.Repository{{Name: "cobra", Url: "https://github.com/spf13/cobra.git"}},
+		GitPath:      "/var/git",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if _, err := client.Sync(ctx, []string{"viper"}); err == nil {
+		t.Errorf("Sync() of an unknown repository, want error")
+	}
+	if _, err := client.Commits(ctx, Query{Repositories: []string{"viper"}}); err == nil {
+		t.Errorf("Commits() of an unknown repository, want error")
+	}
+	if _, err := client.Commits(ctx, Query{Dedup: "subject"}); err == nil {
+		t.Errorf("Commits() with dedup subject, want error")
+	}
+}
+
+// testCommit commits a file in the worktree of repo
+func testCommit(t *testing.T, repo *gogit.Repository, file string, message string, when time.Time) plumbing.Hash {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(worktree.Filesystem.Root(), file), []byte(message), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add(file); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := worktree.Commit(message, &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Jean", Email: "jean@example.com", When: when},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestClientSyncCommits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "followup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	srcRepo, err := gogit.PlainInit(src, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := testCommit(t, srcRepo, "a.txt", "First commit", time.Date(2019, time.May, 1, 12, 0, 0, 0, time.UTC))
+
+	// The same repository used in place by its file:// url, and mirrored from its path
+	client, err := NewClient(Options{
+		Repositories: []git.Repository{
+			{Name: "local", Url: "file://" + src, Labels: []string{"local"}},
+			{Name: "mirror", Url: src},
+		},
+		GitPath:    filepath.Join(dir, "git"),
+		NewCommits: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	results, err := client.Sync(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatalf("Sync() of %v : %v", result.Repository.Name, result.Err)
+		}
+		if len(result.NewCommits) != 0 {
+			t.Errorf("Sync() of %v listed %d new commits on clone, want none", result.Repository.Name, len(result.NewCommits))
+		}
+	}
+
+	second := testCommit(t, srcRepo, "b.txt", "Second commit", time.Date(2019, time.May, 2, 12, 0, 0, 0, time.UTC))
+	results, err = client.Sync(ctx, []string{"mirror"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("Sync() of mirror = %+v, want one successful result", results)
+	}
+	if got := results[0].NewCommits; len(got) != 1 || got[0].Commit.Hash != second {
+		t.Errorf("Sync() of mirror listed new commits %v, want %v", got, second)
+	}
+
+	from := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		query Query
+		want  []plumbing.Hash
+	}{
+		{name: "all repositories", query: Query{From: from}, want: []plumbing.Hash{first, first, second, second}},
+		{name: "repository", query: Query{From: from, Repositories: []string{"mirror"}}, want: []plumbing.Hash{first, second}},
+		{name: "label", query: Query{From: from, Labels: []string{"local"}}, want: []plumbing.Hash{first, second}},
+		{name: "date range", query: Query{From: from, To: time.Date(2019, time.May, 2, 0, 0, 0, 0, time.UTC)}, want: []plumbing.Hash{first, first}},
+		{name: "dedup", query: Query{From: from, Dedup: "hash"}, want: []plumbing.Hash{first, second}},
+		{name: "author", query: Query{From: from, Authors: []string{"paul"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := client.Commits(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []plumbing.Hash
+			for _, c := range commits {
+				got = append(got, c.Commit.Hash)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Commits() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Commits() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	if err := client.WriteCommits(ctx, &out, Query{From: from, Repositories: []string{"mirror"}, Display: []string{"hash"}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], first.String()[:8]) || !strings.Contains(lines[1], second.String()[:8]) ||
+		strings.Contains(out.String(), "Jean") {
+		t.Errorf("WriteCommits() = %q, want the hashes of the commits of mirror only", out.String())
+	}
+
+	out.Reset()
+	if err := client.WriteCommits(ctx, &out, Query{From: from, Repositories: []string{"mirror"}, Output: "atom"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "<feed") || !strings.Contains(out.String(), "Second commit") {
+		t.Errorf("WriteCommits() atom = %q, want a feed of the commits", out.String())
+	}
+
+	for _, q := range []Query{{Output: "json"}, {Display: []string{"files"}}} {
+		if err := client.WriteCommits(ctx, &out, q); err == nil {
+			t.Errorf("WriteCommits(%+v), want error", q)
+		}
+	}
+}
+
+func TestClientSyncCancel(t *testing.T) {
+	gitPath, err := ioutil.TempDir("", "followup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gitPath)
+
+	client, err := NewClient(Options{
+		Repositories: []git.Repository{{Name: "cobra", Url: "https://github.com/spf13/cobra.git"}},
+		GitPath:      gitPath,
+		LockTimeout:  time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another process syncing the repository
+	if err := os.MkdirAll(filepath.Join(gitPath, ".locks"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := git.TryLock(filepath.Join(gitPath, ".locks", "cobra.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	results, err := client.Sync(ctx, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Sync() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(results) != 1 || results[0].Err != context.DeadlineExceeded {
+		t.Errorf("Sync() = %+v, want the error of the context", results)
+	}
+	if waited := time.Since(started); waited > 5*time.Second {
+		t.Errorf("Sync() returned after %v, want once ctx is done", waited)
+	}
+}
@@ -0,0 +1,152 @@
+package git
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	cryptossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+var AuthTypes = []string{"ssh", "ssh_agent", "access_token"}
+
+// Keys tried, in order, when no auth_file is given for a ssh authentication
+var DefaultSSHKeys = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// AuthMethod returns the transport authentication of the repository, nil if none is configured
+func (r Repository) AuthMethod() (transport.AuthMethod, error) {
+	a := r.Authentication
+
+	switch a.Type {
+	case "ssh":
+		keyFile := a.AuthFile
+		if keyFile == "" {
+			var err error
+			keyFile, err = defaultSSHKey()
+			if err != nil {
+				return nil, fmt.Errorf("%v : %v", r.Name, err)
+			}
+		}
+		keyFile, err := homedir.Expand(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%v : %v", r.Name, err)
+		}
+		signer, err := a.loadSSHKey(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%v : ssh key %v : %v", r.Name, keyFile, err)
+		}
+		auth := &ssh.PublicKeys{User: "git", Signer: signer}
+		auth.HostKeyCallback, err = a.HostKeyCallback()
+		if err != nil {
+			return nil, fmt.Errorf("%v : %v", r.Name, err)
+		}
+		return auth, nil
+	case "ssh_agent":
+		auth, err := ssh.NewSSHAgentAuth("git")
+		if err != nil {
+			return nil, fmt.Errorf("%v : ssh agent error: %v", r.Name, err)
+		}
+		auth.HostKeyCallback, err = a.HostKeyCallback()
+		if err != nil {
+			return nil, fmt.Errorf("%v : %v", r.Name, err)
+		}
+		return auth, nil
+	case "access_token":
+		username, accessToken, err := a.accessToken(r.Url)
+		if err != nil {
+			return nil, fmt.Errorf("%v : %v", r.Name, err)
+		}
+		return &http.BasicAuth{
+			Username: username,
+			Password: accessToken,
+		}, nil
+	case "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%v : unknown authentication type %v (%v)", r.Name, a.Type, strings.Join(AuthTypes, ", "))
+	}
+}
+
+func defaultSSHKey() (string, error) {
+	for _, key := range DefaultSSHKeys {
+		path, err := homedir.Expand(key)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no ssh key found (%v)", strings.Join(DefaultSSHKeys, ", "))
+}
+
+// loadSSHKey parses a private key, decrypting it with the configured passphrase if needed
+func (a Authentication) loadSSHKey(keyFile string) (cryptossh.Signer, error) {
+	pemBytes, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := cryptossh.ParsePrivateKey(pemBytes)
+	if _, ok := err.(*cryptossh.PassphraseMissingError); !ok {
+		return signer, err
+	}
+
+	passphrase, err := a.passphrase(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, err = cryptossh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	if err == x509.IncorrectPasswordError {
+		return nil, fmt.Errorf("incorrect passphrase")
+	}
+	return signer, err
+}
+
+var promptMutex sync.Mutex
+var promptedPassphrases = make(map[string]string)
+
+// passphrase reads the passphrase of an encrypted key from its configured source :
+// file:<path>, env:<VAR>, cmd:<command> or prompt. Without source, the passphrase is prompted if stdin is a terminal.
+func (a Authentication) passphrase(keyFile string) (string, error) {
+	source := a.Passphrase
+	switch {
+	case source == "prompt" || source == "":
+		if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return "", fmt.Errorf("key is encrypted, and no passphrase is configured")
+		}
+		return promptPassphrase(keyFile)
+	default:
+		passphrase, err := readSecret(source)
+		if err != nil {
+			return "", fmt.Errorf("passphrase error: %v", err)
+		}
+		return passphrase, nil
+	}
+}
+
+// promptPassphrase asks for the passphrase of a key once, repos being synced concurrently
+func promptPassphrase(keyFile string) (string, error) {
+	promptMutex.Lock()
+	defer promptMutex.Unlock()
+
+	if passphrase, ok := promptedPassphrases[keyFile]; ok {
+		return passphrase, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Enter passphrase for %v: ", keyFile)
+	passphrase, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	promptedPassphrases[keyFile] = string(passphrase)
+	return string(passphrase), nil
+}
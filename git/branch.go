@@ -0,0 +1,204 @@
+package git
+
+import (
+	"container/heap"
+	"fmt"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Branch describes the activity of a branch compared to the default branch of its repository
+type Branch struct {
+	Name       string
+	Repository string
+	Default    bool
+	LastCommit *object.Commit
+	// Number of commits not in the default branch
+	Ahead int
+	// Number of commits of the default branch missing from the branch
+	Behind int
+	Merged bool
+}
+
+// ListBranches lists the branches of the local copy, with ahead/behind counts against the default branch
+func (r Repository) ListBranches() (branches []Branch, e error) {
+
+	gitRepo, err := git.PlainOpen(r.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+
+	head, err := gitRepo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+
+	refs, err := gitRepo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		commit, err := gitRepo.CommitObject(ref.Hash())
+		if err != nil {
+			return err
+		}
+
+		branch := Branch{
+			Name:       ref.Name().Short(),
+			Repository: r.Name,
+			Default:    ref.Name() == head.Name(),
+			LastCommit: commit,
+		}
+
+		if !branch.Default {
+			branch.Ahead, branch.Behind, err = aheadBehind(gitRepo, ref.Hash(), head.Hash())
+			if err != nil {
+				return err
+			}
+		}
+		branch.Merged = branch.Ahead == 0
+
+		branches = append(branches, branch)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+
+	return branches, nil
+}
+
+// aheadBehind counts the commits reachable from branch but not from base, and the reverse, as
+// `git rev-list --left-right --count` does : both histories are walked from the most recent commit,
+// and the walk stops once the remaining commits are reachable from both, at their merge base.
+// Commits dated before their parents may be miscounted.
+func aheadBehind(gitRepo *git.Repository, branch plumbing.Hash, base plumbing.Hash) (ahead int, behind int, err error) {
+	const (
+		fromBranch = 1 << iota
+		fromBase
+		fromBoth = fromBranch | fromBase
+	)
+	flags := make(map[plumbing.Hash]int)
+	visited := make(map[plumbing.Hash]bool)
+	var queue commitQueue
+
+	push := func(hash plumbing.Hash, flag int) error {
+		if flags[hash]|flag == flags[hash] {
+			return nil
+		}
+		flags[hash] |= flag
+		commit, err := gitRepo.CommitObject(hash)
+		if err != nil {
+			return err
+		}
+		heap.Push(&queue, commit)
+		return nil
+	}
+	if err := push(branch, fromBranch); err != nil {
+		return 0, 0, err
+	}
+	if err := push(base, fromBase); err != nil {
+		return 0, 0, err
+	}
+
+	for queue.Len() > 0 && !queue.stale(flags, fromBoth) {
+		commit := heap.Pop(&queue).(*object.Commit)
+		if visited[commit.Hash] {
+			continue
+		}
+		visited[commit.Hash] = true
+
+		flag := flags[commit.Hash]
+		switch flag {
+		case fromBranch:
+			ahead++
+			break
+		case fromBase:
+			behind++
+			break
+		}
+		for _, parent := range commit.ParentHashes {
+			if err := push(parent, flag); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	return ahead, behind, nil
+}
+
+// commitQueue is a heap of commits, the most recent first
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int {
+	return len(q)
+}
+
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+
+func (q commitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *commitQueue) Push(x interface{}) {
+	*q = append(*q, x.(*object.Commit))
+}
+
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	commit := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return commit
+}
+
+// stale returns whether every queued commit has all the flags, their ancestors having them too
+func (q commitQueue) stale(flags map[plumbing.Hash]int, all int) bool {
+	for _, commit := range q {
+		if flags[commit.Hash] != all {
+			return false
+		}
+	}
+	return true
+}
+
+// ByLastCommit sorts branches by date of their last commit
+type ByLastCommit []Branch
+
+func (s ByLastCommit) Len() int {
+	return len(s)
+}
+
+func (s ByLastCommit) Less(i, j int) bool {
+	return s[i].LastCommit.Committer.When.Before(s[j].LastCommit.Committer.When)
+}
+
+func (s ByLastCommit) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+var ageRegex = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+
+// ParseAge parses an age such as 12h, 30d, 2w, 6m or 1y.
+// Months and years are counted as 30 and 365 days.
+func ParseAge(age string) (time.Duration, error) {
+	matches := ageRegex.FindStringSubmatch(age)
+	if matches == nil {
+		return 0, fmt.Errorf("age not recognized : %s (expected a number followed by h, d, w, m or y)", age)
+	}
+	n, _ := strconv.Atoi(matches[1])
+	day := 24 * time.Hour
+	units := map[string]time.Duration{
+		"h": time.Hour,
+		"d": day,
+		"w": 7 * day,
+		"m": 30 * day,
+		"y": 365 * day,
+	}
+	return time.Duration(n) * units[matches[2]], nil
+}
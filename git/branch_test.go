@@ -0,0 +1,100 @@
+package git
+
+import (
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListBranches(t *testing.T) {
+	dir, repo := initTestRepo(t)
+	day := func(d int) time.Time {
+		return time.Date(2019, time.May, d, 12, 0, 0, 0, time.UTC)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkout := func(branch string) {
+		if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// master : c1 - c2 - merge of feature - c3
+	c1 := testCommit(t, repo, "main.go", "v1", "First commit", day(1))
+	testBranch(t, repo, "feature", c1)
+	f1 := testCommit(t, repo, "feature.go", "feature", "Add the feature", day(2))
+	testBranch(t, repo, "stale", c1)
+	testCommit(t, repo, "stale.go", "stale", "Abandoned work", day(3))
+	testBranch(t, repo, "fast-forward", c1)
+	checkout("master")
+	c2 := testCommit(t, repo, "main.go", "v2", "Second commit", day(4))
+	if err := ioutil.WriteFile(filepath.Join(dir, "feature.go"), []byte("feature"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("feature.go"); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "Jean", Email: "jean@example.com", When: day(5)}
+	if _, err := worktree.Commit("Merge feature", &git.CommitOptions{Author: signature, Parents: []plumbing.Hash{c2, f1}}); err != nil {
+		t.Fatal(err)
+	}
+	testCommit(t, repo, "main.go", "v3", "Third commit", day(6))
+
+	branches, err := Repository{Name: "app", LocalPath: dir}.ListBranches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type counts struct {
+		Default       bool
+		Ahead, Behind int
+		Merged        bool
+	}
+	want := map[string]counts{
+		"master":       {Default: true, Merged: true},
+		"feature":      {Ahead: 0, Behind: 3, Merged: true},
+		"fast-forward": {Ahead: 0, Behind: 4, Merged: true},
+		"stale":        {Ahead: 1, Behind: 4},
+	}
+	if len(branches) != len(want) {
+		t.Fatalf("ListBranches() = %d branches, want %d", len(branches), len(want))
+	}
+	for _, branch := range branches {
+		got := counts{Default: branch.Default, Ahead: branch.Ahead, Behind: branch.Behind, Merged: branch.Merged}
+		if got != want[branch.Name] {
+			t.Errorf("branch %v = %+v, want %+v", branch.Name, got, want[branch.Name])
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{age: "12h", want: 12 * time.Hour},
+		{age: "30d", want: 30 * 24 * time.Hour},
+		{age: "2w", want: 14 * 24 * time.Hour},
+		{age: "6m", want: 180 * 24 * time.Hour},
+		{age: "1y", want: 365 * 24 * time.Hour},
+		{age: "30", wantErr: true},
+		{age: "d30", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.age, func(t *testing.T) {
+			got, err := ParseAge(tt.age)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
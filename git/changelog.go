@@ -0,0 +1,108 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ConventionalCommit holds the parts of a commit subject following the
+// Conventional Commits spec : type(scope)!: description
+type ConventionalCommit struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+var conventionalRegex = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?: (.+)$`)
+var breakingRegex = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+
+// ParseConventionalCommit parses a commit message.
+// ok is false when the subject doesn't follow the Conventional Commits spec.
+func ParseConventionalCommit(message string) (cc ConventionalCommit, ok bool) {
+	subject := strings.TrimSpace(strings.Split(message, "\n")[0])
+	matches := conventionalRegex.FindStringSubmatch(subject)
+	if matches == nil {
+		cc.Description = subject
+		cc.Breaking = breakingRegex.MatchString(message)
+		return cc, false
+	}
+
+	cc.Type = strings.ToLower(matches[1])
+	cc.Scope = matches[2]
+	cc.Breaking = matches[3] == "!" || breakingRegex.MatchString(message)
+	cc.Description = matches[4]
+	return cc, true
+}
+
+// Changelog sections, in display order
+var ChangelogSections = []string{"Breaking changes", "Features", "Fixes", "Other"}
+
+// Changelog groups commits by section
+type Changelog struct {
+	Title    string
+	Sections map[string][]Commit
+}
+
+func NewChangelog(title string, commits []Commit) (cl *Changelog) {
+	cl = &Changelog{
+		Title:    title,
+		Sections: make(map[string][]Commit),
+	}
+	for _, c := range commits {
+		section := changelogSection(c.Commit.Message)
+		cl.Sections[section] = append(cl.Sections[section], c)
+	}
+	return
+}
+
+func changelogSection(message string) string {
+	cc, ok := ParseConventionalCommit(message)
+	switch {
+	case cc.Breaking:
+		return "Breaking changes"
+	case ok && cc.Type == "feat":
+		return "Features"
+	case ok && cc.Type == "fix":
+		return "Fixes"
+	default:
+		return "Other"
+	}
+}
+
+// Markdown renders the changelog, optionally crediting authors and short hashes
+func (cl Changelog) Markdown(withAuthors bool, withHashes bool) (result string) {
+	result += "## " + cl.Title + "\n"
+	for _, section := range ChangelogSections {
+		commits := cl.Sections[section]
+		if len(commits) == 0 {
+			continue
+		}
+		result += "\n### " + section + "\n\n"
+		for _, c := range commits {
+			result += "- " + changelogEntry(c, withAuthors, withHashes) + "\n"
+		}
+	}
+	return result
+}
+
+func changelogEntry(c Commit, withAuthors bool, withHashes bool) (entry string) {
+	cc, ok := ParseConventionalCommit(c.Commit.Message)
+	if ok && cc.Scope != "" {
+		entry += "**" + cc.Scope + ":** "
+	}
+	entry += cc.Description
+
+	var details []string
+	if withHashes {
+		details = append(details, c.ShortHash())
+	}
+	if withAuthors {
+		details = append(details, c.Commit.Author.Name)
+	}
+	if len(details) > 0 {
+		entry += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+	}
+	return entry
+}
@@ -0,0 +1,53 @@
+package git
+
+import (
+	"testing"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    ConventionalCommit
+		wantOk  bool
+	}{
+		{
+			name:    "feature with scope",
+			message: "feat(parser): add array support",
+			want:    ConventionalCommit{Type: "feat", Scope: "parser", Description: "add array support"},
+			wantOk:  true,
+		},
+		{
+			name:    "fix without scope",
+			message: "fix: handle empty config\n\nSome details",
+			want:    ConventionalCommit{Type: "fix", Description: "handle empty config"},
+			wantOk:  true,
+		},
+		{
+			name:    "breaking change marker",
+			message: "refactor(api)!: drop v1 endpoints",
+			want:    ConventionalCommit{Type: "refactor", Scope: "api", Breaking: true, Description: "drop v1 endpoints"},
+			wantOk:  true,
+		},
+		{
+			name:    "breaking change footer",
+			message: "feat: new config format\n\nBREAKING CHANGE: old files must be migrated",
+			want:    ConventionalCommit{Type: "feat", Breaking: true, Description: "new config format"},
+			wantOk:  true,
+		},
+		{
+			name:    "not conventional",
+			message: "Merge branch 'master' into dev",
+			want:    ConventionalCommit{Description: "Merge branch 'master' into dev"},
+			wantOk:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := ParseConventionalCommit(tt.message)
+			if got != tt.want || gotOk != tt.wantOk {
+				t.Errorf("ParseConventionalCommit() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
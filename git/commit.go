@@ -11,6 +11,11 @@ type Commit struct {
 	Commit     *object.Commit
 	Repository *git.Repository
 	Name       string
+	Issues     []IssueRef
+	// Branches containing the commit, only set by Dedup
+	Branches []string
+	// Same commits found in other repos or branches, only set by Dedup
+	Duplicates []Commit
 }
 
 func NewCommit(c *object.Commit, r *git.Repository, name string) (commit *Commit) {
@@ -21,12 +26,78 @@ func NewCommit(c *object.Commit, r *git.Repository, name string) (commit *Commit
 	}
 }
 
+// Subject returns the first line of the commit message
+func (c Commit) Subject() string {
+	return strings.Split(c.Commit.Message, "\n")[0]
+}
+
+// ShortHash returns the abbreviated commit hash
+func (c Commit) ShortHash() string {
+	return c.Commit.Hash.String()[:8]
+}
+
+// Diff returns the patch of the commit, compared to its first parent
+func (c Commit) Diff() (string, error) {
+	patch, err := commitPatch(c.Commit)
+	if err != nil {
+		return "", err
+	}
+	return patch.String(), nil
+}
+
+func commitPatch(c *object.Commit) (*object.Patch, error) {
+	changes, err := commitChanges(c)
+	if err != nil {
+		return nil, err
+	}
+	return changes.Patch()
+}
+
+// Files returns the paths changed by the commit, compared to its first parent
+func (c Commit) Files() ([]string, error) {
+	changes, err := commitChanges(c.Commit)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, change := range changes {
+		if change.From.Name != "" {
+			files = append(files, change.From.Name)
+		}
+		if change.To.Name != "" && change.To.Name != change.From.Name {
+			files = append(files, change.To.Name)
+		}
+	}
+	return files, nil
+}
+
+func commitChanges(c *object.Commit) (object.Changes, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		parentTree, err = parent.Tree()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return object.DiffTree(parentTree, tree)
+}
+
 func (c Commit) String() string {
-	message := strings.Split(c.Commit.Message, "\n")[0]
+	message := c.Subject()
 	if len(message) > 70 {
 		message = message[:70]+"..."
 	}
-	hash := c.Commit.Hash.String()[:8]
+	hash := c.ShortHash()
 	author := c.Commit.Author.Name
 	date := c.Commit.Author.When.Format("2006-01-02 15:04")
 	name := c.Name
@@ -35,6 +106,50 @@ func (c Commit) String() string {
 	return fmt.Sprintf("\033[1;31m[%s\t]\033[0m\033[1;36m[%s]\t\033[0m\033[1;34m[%s]\t\033[0m %s \033[1;32m\t(%s)\033[0m", name, date, hash, message, author)
 }
 
+// OutputArgs are the formats the commits are written in
+var OutputArgs = []string{"text", "atom"}
+
+// Format returns the fields of the commit listed in display, as printed by the commits command
+func (c Commit) Format(display []string) (result string) {
+	message := c.Subject()
+	if len(message) > 70 {
+		message = message[:70] + "..."
+	}
+	hash := c.ShortHash()
+	author := c.Commit.Author.Name
+	for _, coAuthor := range CoAuthors(c.Commit.Message) {
+		author += ", " + IdentityName(coAuthor)
+	}
+	date := c.Commit.Author.When.Format("2006-01-02 15:04")
+	name := c.Name
+	if len(c.Branches) > 0 || len(c.Duplicates) > 0 {
+		name = strings.Join(c.Locations(), ", ")
+	}
+
+	// Color reference : https://stackoverflow.com/questions/5947742/how-to-change-the-output-color-of-echo-in-linux
+	if Contains(display, "repo") {
+		result += "\033[1;31m" + name + "\t \033[0m"
+	}
+
+	if Contains(display, "date") {
+		result += "\033[1;36m" + date + "\t \033[0m"
+	}
+
+	if Contains(display, "hash") {
+		result += "\033[1;34m" + hash + "\t\033[0m"
+	}
+
+	if Contains(display, "message") {
+		result += " " + message + " \t"
+	}
+
+	if Contains(display, "author") {
+		result += "\033[1;32m" + author + "\033[0m"
+	}
+
+	return result
+}
+
 type ByDate []Commit
 
 func (s ByDate) Len() int {
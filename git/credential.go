@@ -0,0 +1,201 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Username sent with an access token when neither configured nor provided by the credential source.
+// Most git hosting platforms ignore it.
+const DefaultTokenUsername = "anything"
+
+// readSecret reads a secret from its source : file:<path>, env:<VAR> or cmd:<command>.
+// A trailing newline is trimmed.
+func readSecret(source string) (string, error) {
+	switch {
+	case strings.HasPrefix(source, "file:"):
+		path, err := homedir.Expand(strings.TrimPrefix(source, "file:"))
+		if err != nil {
+			return "", err
+		}
+		secret, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("file error: %v", err)
+		}
+		return strings.TrimRight(string(secret), "\r\n"), nil
+	case strings.HasPrefix(source, "env:"):
+		name := strings.TrimPrefix(source, "env:")
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %v is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(source, "cmd:"):
+		command := strings.TrimPrefix(source, "cmd:")
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stderr = os.Stderr
+		secret, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("command %q error: %v", command, err)
+		}
+		return strings.TrimRight(string(secret), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("source not recognized : %v", source)
+	}
+}
+
+// accessToken returns the username and token used to authenticate over https.
+// The token source is file:<path>, env:<VAR>, cmd:<command>, git-credential or netrc, auth_file being a file source.
+func (a Authentication) accessToken(repoUrl string) (username string, token string, err error) {
+	source := a.Token
+	if source == "" {
+		if a.AuthFile == "" {
+			return "", "", fmt.Errorf("token or auth_file is required")
+		}
+		source = "file:" + a.AuthFile
+	}
+
+	switch source {
+	case "git-credential":
+		username, token, err = credentialFill(repoUrl)
+	case "netrc":
+		username, token, err = netrcLookup(repoUrl)
+	default:
+		token, err = readSecret(source)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("token error: %v", err)
+	}
+
+	if a.Username != "" {
+		username = a.Username
+	}
+	if username == "" {
+		username = DefaultTokenUsername
+	}
+	return username, token, nil
+}
+
+// credentialFill asks the configured git credential helpers for credentials, see git-credential(1)
+func credentialFill(repoUrl string) (username string, password string, err error) {
+	u, err := url.Parse(repoUrl)
+	if err != nil {
+		return "", "", err
+	}
+
+	input := fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n", u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/"))
+	if u.User != nil {
+		input += "username=" + u.User.Username() + "\n"
+	}
+
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(input + "\n")
+	cmd.Stderr = os.Stderr
+	// Never prompt, credentials have to be stored by a helper
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("git credential fill error: %v", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "username":
+			username = kv[1]
+		case "password":
+			password = kv[1]
+		}
+	}
+	if password == "" {
+		return "", "", fmt.Errorf("no credentials found for %v", u.Host)
+	}
+	return username, password, nil
+}
+
+// netrcLookup reads the login and password of the repository host in ~/.netrc, or the file set by $NETRC
+func netrcLookup(repoUrl string) (login string, password string, err error) {
+	u, err := url.Parse(repoUrl)
+	if err != nil {
+		return "", "", err
+	}
+
+	path := os.Getenv("NETRC")
+	if path == "" {
+		path, err = homedir.Expand("~/.netrc")
+		if err != nil {
+			return "", "", err
+		}
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("netrc error: %v", err)
+	}
+
+	login, password, found := parseNetrc(string(content), u.Hostname())
+	if !found {
+		return "", "", fmt.Errorf("no %v machine in %v", u.Hostname(), path)
+	}
+	return login, password, nil
+}
+
+type netrcEntry struct {
+	machine   string
+	isDefault bool
+	login     string
+	password  string
+}
+
+// parseNetrc returns the credentials of machine, or of the default entry
+func parseNetrc(content string, machine string) (login string, password string, found bool) {
+	var entries []netrcEntry
+	fields := strings.Fields(content)
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			entries = append(entries, netrcEntry{})
+			if i+1 < len(fields) {
+				i++
+				entries[len(entries)-1].machine = fields[i]
+			}
+		case "default":
+			entries = append(entries, netrcEntry{isDefault: true})
+		case "login", "password", "account", "macdef":
+			if i+1 >= len(fields) {
+				break
+			}
+			i++
+			if len(entries) == 0 {
+				continue
+			}
+			if fields[i-1] == "login" {
+				entries[len(entries)-1].login = fields[i]
+			} else if fields[i-1] == "password" {
+				entries[len(entries)-1].password = fields[i]
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		if !entry.isDefault && entry.machine == machine {
+			return entry.login, entry.password, true
+		}
+	}
+	for _, entry := range entries {
+		if entry.isDefault {
+			return entry.login, entry.password, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,181 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeGit answers git credential fill according to the host of the request, as a credential helper would
+const fakeGit = `#!/bin/sh
+if [ "$1 $2" != "credential fill" ] || [ "$GIT_TERMINAL_PROMPT" != "0" ]; then
+	exit 2
+fi
+input=$(cat)
+case "$input" in
+*host=github.com*path=acme/api*username=bot*)
+	printf 'protocol=https\nhost=github.com\nusername=bot\npassword=bot-token\n' ;;
+*host=github.com*path=acme/api*)
+	printf 'protocol=https\nhost=github.com\nusername=jean\npassword=gh-token\n' ;;
+*host=gitlab.com*)
+	printf 'password=gl=token\n' ;;
+*host=malformed.example.com*)
+	printf 'garbage\npassword\nusername=jean\n\n' ;;
+*host=crash.example.com*)
+	echo "fatal: helper crashed" >&2
+	exit 128 ;;
+esac
+`
+
+// withFakeGit puts the fake git first in PATH until the test ends, returning its directory
+func withFakeGit(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake git is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "git"), []byte(fakeGit), 0700); err != nil {
+		t.Fatal(err)
+	}
+	savedPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+savedPath)
+	t.Cleanup(func() {
+		os.Setenv("PATH", savedPath)
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+func TestCredentialFill(t *testing.T) {
+	withFakeGit(t)
+
+	tests := []struct {
+		url          string
+		wantUsername string
+		wantPassword string
+		wantErr      string
+	}{
+		{url: "https://github.com/acme/api.git", wantUsername: "jean", wantPassword: "gh-token"},
+		{url: "https://bot@github.com/acme/api.git", wantUsername: "bot", wantPassword: "bot-token"},
+		{url: "https://gitlab.com/acme/api.git", wantPassword: "gl=token"},
+		{url: "https://malformed.example.com/acme/api.git", wantErr: "no credentials found for malformed.example.com"},
+		{url: "https://unknown.example.com/acme/api.git", wantErr: "no credentials found for unknown.example.com"},
+		{url: "https://crash.example.com/acme/api.git", wantErr: "git credential fill error: exit status 128"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			username, password, err := credentialFill(tt.url)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("credentialFill() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if username != tt.wantUsername || password != tt.wantPassword {
+				t.Errorf("credentialFill() = %v, %v, want %v, %v", username, password, tt.wantUsername, tt.wantPassword)
+			}
+		})
+	}
+}
+
+func TestAccessToken(t *testing.T) {
+	bin := withFakeGit(t)
+	writeTestFile(t, filepath.Join(bin, "token-helper"), "#!/bin/sh\necho \"helper-token-$1\"\n")
+	if err := os.Chmod(filepath.Join(bin, "token-helper"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	writeTestFile(t, tokenFile, "file-token\n")
+	os.Setenv("GIT_FOLLOW_UP_TEST_TOKEN", "env-token")
+	defer os.Unsetenv("GIT_FOLLOW_UP_TEST_TOKEN")
+
+	const repoUrl = "https://github.com/acme/api.git"
+	tests := []struct {
+		name         string
+		auth         Authentication
+		wantUsername string
+		wantToken    string
+		wantErr      string
+	}{
+		{name: "file", auth: Authentication{Token: "file:" + tokenFile}, wantUsername: DefaultTokenUsername, wantToken: "file-token"},
+		{name: "auth_file", auth: Authentication{AuthFile: tokenFile, Username: "jean"}, wantUsername: "jean", wantToken: "file-token"},
+		{name: "env", auth: Authentication{Token: "env:GIT_FOLLOW_UP_TEST_TOKEN"}, wantUsername: DefaultTokenUsername, wantToken: "env-token"},
+		{name: "cmd", auth: Authentication{Token: "cmd:printf 'cmd-token\\r\\n'"}, wantUsername: DefaultTokenUsername, wantToken: "cmd-token"},
+		{name: "cmd helper in PATH", auth: Authentication{Token: "cmd:token-helper api"}, wantUsername: DefaultTokenUsername, wantToken: "helper-token-api"},
+		{name: "cmd with spaces", auth: Authentication{Token: "cmd:echo cmd token"}, wantUsername: DefaultTokenUsername, wantToken: "cmd token"},
+		{name: "git-credential", auth: Authentication{Token: "git-credential"}, wantUsername: "jean", wantToken: "gh-token"},
+		{name: "git-credential and username", auth: Authentication{Token: "git-credential", Username: "paul"}, wantUsername: "paul", wantToken: "gh-token"},
+		{name: "missing source", auth: Authentication{}, wantErr: "token or auth_file is required"},
+		{name: "missing file", auth: Authentication{Token: "file:" + filepath.Join(dir, "missing")}, wantErr: "token error: file error: open " + filepath.Join(dir, "missing") + ": no such file or directory"},
+		{name: "unset env", auth: Authentication{Token: "env:GIT_FOLLOW_UP_TEST_UNSET"}, wantErr: "token error: environment variable GIT_FOLLOW_UP_TEST_UNSET is not set"},
+		{name: "failing cmd", auth: Authentication{Token: "cmd:exit 3"}, wantErr: `token error: command "exit 3" error: exit status 3`},
+		{name: "unknown source", auth: Authentication{Token: "vault:token"}, wantErr: "token error: source not recognized : vault:token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, token, err := tt.auth.accessToken(repoUrl)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("accessToken() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if username != tt.wantUsername || token != tt.wantToken {
+				t.Errorf("accessToken() = %v, %v, want %v, %v", username, token, tt.wantUsername, tt.wantToken)
+			}
+		})
+	}
+
+	// Credentials of malformed helper output aren't used
+	if _, _, err := (Authentication{Token: "git-credential"}).accessToken("https://malformed.example.com/acme/api.git"); err == nil || !strings.Contains(err.Error(), "no credentials found") {
+		t.Errorf("accessToken() of malformed helper output, error = %v", err)
+	}
+}
+
+func TestParseNetrc(t *testing.T) {
+	content := `machine github.com
+  login jean
+  password gh-token
+
+machine gitlab.com login jack password gl-token
+default login anonymous password none
+`
+	tests := []struct {
+		machine      string
+		wantLogin    string
+		wantPassword string
+		wantFound    bool
+	}{
+		{machine: "github.com", wantLogin: "jean", wantPassword: "gh-token", wantFound: true},
+		{machine: "gitlab.com", wantLogin: "jack", wantPassword: "gl-token", wantFound: true},
+		{machine: "gitea.com", wantLogin: "anonymous", wantPassword: "none", wantFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.machine, func(t *testing.T) {
+			login, password, found := parseNetrc(content, tt.machine)
+			if login != tt.wantLogin || password != tt.wantPassword || found != tt.wantFound {
+				t.Errorf("parseNetrc() = %v, %v, %v, want %v, %v, %v", login, password, found, tt.wantLogin, tt.wantPassword, tt.wantFound)
+			}
+		})
+	}
+
+	if _, _, found := parseNetrc("machine github.com login jean password x", "gitlab.com"); found {
+		t.Errorf("parseNetrc() found credentials of an unknown machine")
+	}
+}
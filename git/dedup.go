@@ -0,0 +1,182 @@
+package git
+
+import (
+	"container/heap"
+	"crypto/sha1"
+	"fmt"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var DedupArgs = []string{"hash", "patch-id"}
+
+// Dedup collapses commits having the same hash, or the same patch id when byPatchID is set,
+// so that a change found in several repos (forks, mirrors) or cherry-picked across branches is listed once.
+// Kept commits are annotated with their duplicates and the branches containing them.
+func Dedup(commits []Commit, byPatchID bool) (deduped []Commit, err error) {
+	index := make(map[string]int)
+	for _, c := range commits {
+		key := c.Commit.Hash.String()
+		if byPatchID {
+			key, err = PatchID(c.Commit)
+			if err != nil {
+				return nil, fmt.Errorf("%v : patch id of %v : %v", c.Name, c.ShortHash(), err)
+			}
+		}
+
+		if i, ok := index[key]; ok {
+			deduped[i].Duplicates = append(deduped[i].Duplicates, c)
+			continue
+		}
+		index[key] = len(deduped)
+		deduped = append(deduped, c)
+	}
+
+	// Commits to annotate, by repository
+	targets := make(map[*git.Repository]map[plumbing.Hash]*object.Commit)
+	for _, c := range deduped {
+		for _, commit := range append([]Commit{c}, c.Duplicates...) {
+			if targets[commit.Repository] == nil {
+				targets[commit.Repository] = make(map[plumbing.Hash]*object.Commit)
+			}
+			targets[commit.Repository][commit.Commit.Hash] = commit.Commit
+		}
+	}
+	branchIndexes := make(map[*git.Repository]map[plumbing.Hash][]string)
+	annotate := func(c *Commit) error {
+		branches, ok := branchIndexes[c.Repository]
+		if !ok {
+			branches, err = branchesContaining(c.Repository, targets[c.Repository])
+			if err != nil {
+				return fmt.Errorf("%v : %v", c.Name, err)
+			}
+			branchIndexes[c.Repository] = branches
+		}
+		c.Branches = branches[c.Commit.Hash]
+		return nil
+	}
+	for i := range deduped {
+		if err := annotate(&deduped[i]); err != nil {
+			return nil, err
+		}
+		for j := range deduped[i].Duplicates {
+			if err := annotate(&deduped[i].Duplicates[j]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return deduped, nil
+}
+
+// branchesContaining maps the target commits to the names of the branches containing them.
+// Each branch is walked from the most recent commit, until every target is found or older than the remaining commits,
+// so that only the history covering the targets is read. Commits dated before their parents may be missed.
+func branchesContaining(gitRepo *git.Repository, targets map[plumbing.Hash]*object.Commit) (map[plumbing.Hash][]string, error) {
+	index := make(map[plumbing.Hash][]string)
+	if len(targets) == 0 {
+		return index, nil
+	}
+	var oldest time.Time
+	for _, c := range targets {
+		if oldest.IsZero() || c.Committer.When.Before(oldest) {
+			oldest = c.Committer.When
+		}
+	}
+
+	refs, err := gitRepo.Branches()
+	if err != nil {
+		return nil, err
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		head, err := gitRepo.CommitObject(ref.Hash())
+		if err != nil {
+			return err
+		}
+		queue := commitQueue{head}
+		seen := map[plumbing.Hash]bool{head.Hash: true}
+		found := 0
+		for queue.Len() > 0 && found < len(targets) {
+			c := heap.Pop(&queue).(*object.Commit)
+			if c.Committer.When.Before(oldest) {
+				break
+			}
+			if _, ok := targets[c.Hash]; ok {
+				index[c.Hash] = append(index[c.Hash], ref.Name().Short())
+				found++
+			}
+			for _, parent := range c.ParentHashes {
+				if seen[parent] {
+					continue
+				}
+				seen[parent] = true
+				commit, err := gitRepo.CommitObject(parent)
+				if err != nil {
+					return err
+				}
+				heap.Push(&queue, commit)
+			}
+		}
+		return nil
+	})
+	return index, err
+}
+
+// PatchID identifies the change introduced by a commit compared to its first parent,
+// similarly to `git patch-id` : whitespaces and line numbers are ignored,
+// so cherry-picked commits share the same patch id.
+func PatchID(c *object.Commit) (string, error) {
+	patch, err := commitPatch(c)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha1.New()
+	for _, line := range strings.Split(patch.String(), "\n") {
+		if strings.HasPrefix(line, "index ") {
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			line = "@@"
+		}
+		h.Write([]byte(strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, line)))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// Locations lists the repos, and the branches in parentheses, containing the commit or its duplicates
+func (c Commit) Locations() (locations []string) {
+	var names []string
+	branches := make(map[string][]string)
+	for _, commit := range append([]Commit{c}, c.Duplicates...) {
+		if _, ok := branches[commit.Name]; !ok {
+			names = append(names, commit.Name)
+		}
+		for _, branch := range commit.Branches {
+			if !Contains(branches[commit.Name], branch) {
+				branches[commit.Name] = append(branches[commit.Name], branch)
+			}
+		}
+		if branches[commit.Name] == nil {
+			branches[commit.Name] = []string{}
+		}
+	}
+
+	for _, name := range names {
+		if len(branches[name]) == 0 {
+			locations = append(locations, name)
+			continue
+		}
+		locations = append(locations, name+" ("+strings.Join(branches[name], ", ")+")")
+	}
+	return locations
+}
@@ -0,0 +1,108 @@
+package git
+
+import (
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testBranch creates a branch starting at hash and checks it out
+func testBranch(t *testing.T, repo *git.Repository, name string, hash plumbing.Hash) {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(name), Hash: hash, Create: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	_, repo := initTestRepo(t)
+	base := testCommit(t, repo, "main.go", "package main\n", "Initial commit", time.Date(2019, time.May, 1, 12, 0, 0, 0, time.UTC))
+
+	// The same fix cherry-picked onto two release branches, with different hashes
+	var picks []Commit
+	for i, branch := range []string{"release-1", "release-2"} {
+		testBranch(t, repo, branch, base)
+		hash := testCommit(t, repo, "fix.go", "package main\n\n// fix\n", "Fix the build", time.Date(2019, time.May, 2+i, 12, 0, 0, 0, time.UTC))
+		c, err := repo.CommitObject(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		picks = append(picks, Commit{Commit: c, Repository: repo, Name: "app"})
+	}
+
+	// Later work on a branch, walked past to find the pick
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("release-1")}); err != nil {
+		t.Fatal(err)
+	}
+	testCommit(t, repo, "main.go", "package main\n\n// 1.0.1\n", "Release 1.0.1", time.Date(2019, time.May, 5, 12, 0, 0, 0, time.UTC))
+
+	if picks[0].Commit.Hash == picks[1].Commit.Hash {
+		t.Fatalf("cherry-picks share the hash %v", picks[0].Commit.Hash)
+	}
+
+	tests := []struct {
+		name          string
+		byPatchID     bool
+		wantHashes    []plumbing.Hash
+		wantLocations [][]string
+	}{
+		{
+			name:          "hash",
+			wantHashes:    []plumbing.Hash{picks[0].Commit.Hash, picks[1].Commit.Hash},
+			wantLocations: [][]string{{"app (release-1)"}, {"app (release-2)"}},
+		},
+		{
+			name:          "patch-id",
+			byPatchID:     true,
+			wantHashes:    []plumbing.Hash{picks[0].Commit.Hash},
+			wantLocations: [][]string{{"app (release-1, release-2)"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deduped, err := Dedup(picks, tt.byPatchID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var hashes []plumbing.Hash
+			var locations [][]string
+			for _, c := range deduped {
+				hashes = append(hashes, c.Commit.Hash)
+				locations = append(locations, c.Locations())
+			}
+			if !reflect.DeepEqual(hashes, tt.wantHashes) {
+				t.Errorf("Dedup() = %v, want %v", hashes, tt.wantHashes)
+			}
+			if !reflect.DeepEqual(locations, tt.wantLocations) {
+				t.Errorf("Locations() = %v, want %v", locations, tt.wantLocations)
+			}
+		})
+	}
+
+	// Patch ids ignore the parent, but not the change
+	first, err := PatchID(picks[0].Commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseCommit, err := repo.CommitObject(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := PatchID(baseCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == other {
+		t.Errorf("PatchID() of different changes = %v for both", first)
+	}
+}
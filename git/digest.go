@@ -0,0 +1,193 @@
+package git
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var DigestFormats = []string{"markdown", "html", "text"}
+
+// Digest summarizes the activity of a period
+type Digest struct {
+	Title string
+	From  time.Time
+	To    time.Time
+	// Commits grouped by repository, sorted by name
+	Repositories  []DigestRepository
+	CommitCount   int
+	Contributors  []AuthorStat
+	Tags          []Tag
+	StaleBranches []Branch
+	FailedSyncs   []SyncFailure
+}
+
+// DigestRepository holds the commits of a repository, most recent first
+type DigestRepository struct {
+	Name    string
+	Commits []Commit
+}
+
+// SyncFailure is the last error of a repository sync
+type SyncFailure struct {
+	Repository string
+	Date       time.Time
+	Error      string
+}
+
+// NewDigest groups the commits by repository, and ranks the top contributors
+func NewDigest(title string, from time.Time, to time.Time, commits []Commit, topContributors int) *Digest {
+	digest := &Digest{Title: title, From: from, To: to, CommitCount: len(commits)}
+
+	index := make(map[string]int)
+	for _, c := range commits {
+		i, ok := index[c.Name]
+		if !ok {
+			i = len(digest.Repositories)
+			index[c.Name] = i
+			digest.Repositories = append(digest.Repositories, DigestRepository{Name: c.Name})
+		}
+		digest.Repositories[i].Commits = append(digest.Repositories[i].Commits, c)
+	}
+	sort.Slice(digest.Repositories, func(i, j int) bool {
+		return digest.Repositories[i].Name < digest.Repositories[j].Name
+	})
+	for _, repo := range digest.Repositories {
+		sort.SliceStable(repo.Commits, func(i, j int) bool {
+			return repo.Commits[i].Commit.Author.When.After(repo.Commits[j].Commit.Author.When)
+		})
+	}
+
+	digest.Contributors = AuthorStats(commits)
+	if len(digest.Contributors) > topContributors {
+		digest.Contributors = digest.Contributors[:topContributors]
+	}
+	return digest
+}
+
+// Render renders the digest as markdown, html or plain text
+func (d Digest) Render(format string) (string, error) {
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case "html":
+		err = htmltemplate.Must(htmltemplate.New("digest").Funcs(digestFuncs).Parse(digestHTMLTemplate)).Execute(&buffer, d)
+		break
+	case "text":
+		err = template.Must(template.New("digest").Funcs(digestFuncs).Parse(digestTextTemplate)).Execute(&buffer, d)
+		break
+	default:
+		err = template.Must(template.New("digest").Funcs(digestFuncs).Parse(digestMarkdownTemplate)).Execute(&buffer, d)
+	}
+	return buffer.String(), err
+}
+
+var digestFuncs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+	"datetime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04")
+	},
+	"plural": func(count int, word string) string {
+		if count == 1 {
+			return "1 " + word
+		}
+		if strings.HasSuffix(word, "y") {
+			return strconv.Itoa(count) + " " + strings.TrimSuffix(word, "y") + "ies"
+		}
+		return strconv.Itoa(count) + " " + word + "s"
+	},
+	"status": func(b Branch) string {
+		if b.Merged {
+			return "merged"
+		}
+		return "unmerged, " + strconv.Itoa(b.Ahead) + " ahead"
+	},
+}
+
+const digestMarkdownTemplate = `# {{.Title}}
+
+{{date .From}} - {{date .To}} : {{plural .CommitCount "commit"}} in {{plural (len .Repositories) "repository"}}
+{{if .Repositories}}
+## Commits
+{{range .Repositories}}
+### {{.Name}}
+
+{{range .Commits}}- {{.Subject}} ({{.ShortHash}}, {{.Commit.Author.Name}}, {{date .Commit.Author.When}})
+{{end}}{{end}}{{end}}{{if .Contributors}}
+## Top contributors
+
+{{range .Contributors}}- {{.Name}} : {{plural .Commits "commit"}}{{if .CoAuthored}}, {{.CoAuthored}} co-authored{{end}}
+{{end}}{{end}}{{if .Tags}}
+## New tags
+
+{{range .Tags}}- {{.Repository}} {{.Name}} ({{date .Date}})
+{{end}}{{end}}{{if .StaleBranches}}
+## Stale branches
+
+{{range .StaleBranches}}- {{.Repository}} {{.Name}} : last commit {{date .LastCommit.Committer.When}} by {{.LastCommit.Author.Name}}, {{status .}}
+{{end}}{{end}}{{if .FailedSyncs}}
+## Failed syncs
+
+{{range .FailedSyncs}}- {{.Repository}} ({{datetime .Date}}) : {{.Error}}
+{{end}}{{end}}`
+
+const digestTextTemplate = `{{.Title}}
+{{date .From}} - {{date .To}} : {{plural .CommitCount "commit"}} in {{plural (len .Repositories) "repository"}}
+{{if .Repositories}}
+COMMITS
+{{range .Repositories}}
+{{.Name}}
+{{range .Commits}}  {{.ShortHash}} {{date .Commit.Author.When}} {{.Commit.Author.Name}} : {{.Subject}}
+{{end}}{{end}}{{end}}{{if .Contributors}}
+TOP CONTRIBUTORS
+{{range .Contributors}}  {{.Name}} : {{plural .Commits "commit"}}{{if .CoAuthored}}, {{.CoAuthored}} co-authored{{end}}
+{{end}}{{end}}{{if .Tags}}
+NEW TAGS
+{{range .Tags}}  {{.Repository}} {{.Name}} ({{date .Date}})
+{{end}}{{end}}{{if .StaleBranches}}
+STALE BRANCHES
+{{range .StaleBranches}}  {{.Repository}} {{.Name}} : last commit {{date .LastCommit.Committer.When}} by {{.LastCommit.Author.Name}}, {{status .}}
+{{end}}{{end}}{{if .FailedSyncs}}
+FAILED SYNCS
+{{range .FailedSyncs}}  {{.Repository}} ({{datetime .Date}}) : {{.Error}}
+{{end}}{{end}}`
+
+const digestHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body style="font-family: sans-serif; color: #24292e;">
+<h1>{{.Title}}</h1>
+<p>{{date .From}} - {{date .To}} : {{plural .CommitCount "commit"}} in {{plural (len .Repositories) "repository"}}</p>
+{{if .Repositories}}<h2>Commits</h2>
+{{range .Repositories}}<h3>{{.Name}}</h3>
+<ul>
+{{range .Commits}}<li>{{.Subject}} <small>(<code>{{.ShortHash}}</code>, {{.Commit.Author.Name}}, {{date .Commit.Author.When}})</small></li>
+{{end}}</ul>
+{{end}}{{end}}{{if .Contributors}}<h2>Top contributors</h2>
+<ol>
+{{range .Contributors}}<li>{{.Name}} : {{plural .Commits "commit"}}{{if .CoAuthored}}, {{.CoAuthored}} co-authored{{end}}</li>
+{{end}}</ol>
+{{end}}{{if .Tags}}<h2>New tags</h2>
+<ul>
+{{range .Tags}}<li>{{.Repository}} <strong>{{.Name}}</strong> ({{date .Date}})</li>
+{{end}}</ul>
+{{end}}{{if .StaleBranches}}<h2>Stale branches</h2>
+<ul>
+{{range .StaleBranches}}<li>{{.Repository}} <strong>{{.Name}}</strong> : last commit {{date .LastCommit.Committer.When}} by {{.LastCommit.Author.Name}}, {{status .}}</li>
+{{end}}</ul>
+{{end}}{{if .FailedSyncs}}<h2>Failed syncs</h2>
+<ul>
+{{range .FailedSyncs}}<li>{{.Repository}} ({{datetime .Date}}) : <span style="color: #cb2431;">{{.Error}}</span></li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`
@@ -0,0 +1,136 @@
+package git
+
+import (
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"strings"
+	"testing"
+	"time"
+)
+
+func digestCommit(repo string, hash string, author string, date string, message string) Commit {
+	when, _ := time.Parse("2006-01-02", date)
+	signature := object.Signature{Name: author, When: when}
+	return Commit{
+		Name: repo,
+		Commit: &object.Commit{
+			Hash:      plumbing.NewHash(hash),
+			Author:    signature,
+			Committer: signature,
+			Message:   message,
+		},
+	}
+}
+
+func TestDigest_Render(t *testing.T) {
+	from, _ := time.Parse("2006-01-02", "2019-06-08")
+	to, _ := time.Parse("2006-01-02", "2019-06-15")
+	commits := []Commit{
+		digestCommit("viper", "1111111111111111111111111111111111111111", "Jean", "2019-06-10", "Fix env <override>"),
+		digestCommit("cobra", "2222222222222222222222222222222222222222", "Marie", "2019-06-11", "Add completion\n\nCo-authored-by: Jean <jean@example.com>"),
+		digestCommit("viper", "3333333333333333333333333333333333333333", "Jean", "2019-06-12", "Add remote config"),
+	}
+	digest := NewDigest("Weekly digest", from, to, commits, 5)
+	digest.Tags = []Tag{{Name: "v1.4.0", Repository: "viper", Date: to}}
+	digest.StaleBranches = []Branch{{Name: "old-feature", Repository: "cobra", LastCommit: commits[1].Commit, Ahead: 3}}
+	digest.FailedSyncs = []SyncFailure{{Repository: "go-git", Date: to, Error: "fetch error: timeout"}}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "markdown",
+			want: `# Weekly digest
+
+2019-06-08 - 2019-06-15 : 3 commits in 2 repositories
+
+## Commits
+
+### cobra
+
+- Add completion (22222222, Marie, 2019-06-11)
+
+### viper
+
+- Add remote config (33333333, Jean, 2019-06-12)
+- Fix env <override> (11111111, Jean, 2019-06-10)
+
+## Top contributors
+
+- Jean : 2 commits, 1 co-authored
+- Marie : 1 commit
+
+## New tags
+
+- viper v1.4.0 (2019-06-15)
+
+## Stale branches
+
+- cobra old-feature : last commit 2019-06-11 by Marie, unmerged, 3 ahead
+
+## Failed syncs
+
+- go-git (2019-06-15 00:00) : fetch error: timeout
+`,
+		},
+		{
+			format: "text",
+			want: `Weekly digest
+2019-06-08 - 2019-06-15 : 3 commits in 2 repositories
+
+COMMITS
+
+cobra
+  22222222 2019-06-11 Marie : Add completion
+
+viper
+  33333333 2019-06-12 Jean : Add remote config
+  11111111 2019-06-10 Jean : Fix env <override>
+
+TOP CONTRIBUTORS
+  Jean : 2 commits, 1 co-authored
+  Marie : 1 commit
+
+NEW TAGS
+  viper v1.4.0 (2019-06-15)
+
+STALE BRANCHES
+  cobra old-feature : last commit 2019-06-11 by Marie, unmerged, 3 ahead
+
+FAILED SYNCS
+  go-git (2019-06-15 00:00) : fetch error: timeout
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := digest.Render(tt.format)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = \n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+
+	html, err := digest.Render("html")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(html, "<li>Fix env &lt;override&gt; <small>") {
+		t.Errorf("Render() html doesn't escape commit subjects :\n%v", html)
+	}
+}
+
+func TestNewDigest_topContributors(t *testing.T) {
+	var commits []Commit
+	for _, author := range []string{"a", "b", "b", "c", "c", "c"} {
+		commits = append(commits, digestCommit("repo", "1111111111111111111111111111111111111111", author, "2019-06-10", "message"))
+	}
+	digest := NewDigest("digest", time.Time{}, time.Time{}, commits, 2)
+	if len(digest.Contributors) != 2 || digest.Contributors[0].Name != "c" || digest.Contributors[1].Name != "b" {
+		t.Errorf("NewDigest() contributors = %v, want c and b", digest.Contributors)
+	}
+}
@@ -0,0 +1,101 @@
+package git
+
+import (
+	"gopkg.in/src-d/go-git.v4"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DiscoverRepositories walks root to find git repositories, up to maxDepth directories deep (unlimited if negative).
+// Directories matching one of the exclude globs, by name or path relative to root, are skipped.
+// Repositories with an origin remote are described by its url, the others by their path.
+// Repositories are named after their directory, sanitized to be valid names, and nested repositories are skipped.
+func DiscoverRepositories(root string, maxDepth int, excludes []string, labels []string) (repos []Repository, err error) {
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped
+			if info != nil && info.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		if path != root && matchAnyGlob(excludes, info.Name(), rel) {
+			return filepath.SkipDir
+		}
+		if maxDepth >= 0 && rel != "." && strings.Count(rel, string(filepath.Separator)) >= maxDepth {
+			return filepath.SkipDir
+		}
+		if !isGitRepository(path) {
+			return nil
+		}
+
+		name := SanitizeName(info.Name())
+		if name == "" {
+			name = "repository"
+		}
+		repo := Repository{
+			Name:   name,
+			Labels: labels,
+		}
+		if url := originUrl(path); url != "" {
+			repo.Url = url
+		} else {
+			repo.Path = path
+		}
+		repos = append(repos, repo)
+		return filepath.SkipDir
+	})
+
+	return repos, err
+}
+
+func matchAnyGlob(globs []string, name string, rel string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isGitRepository returns whether dir is a working tree (.git directory or file) or a bare repository
+func isGitRepository(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return true
+	}
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func originUrl(dir string) string {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return ""
+	}
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return ""
+	}
+	if urls := remote.Config().URLs; len(urls) > 0 {
+		return urls[0]
+	}
+	return ""
+}
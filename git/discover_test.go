@@ -0,0 +1,113 @@
+package git
+
+import (
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDiscoverRepositories(t *testing.T) {
+	root, err := ioutil.TempDir("", "discover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// Repositories by path relative to root, with their origin url if any
+	layout := map[string]string{
+		"app":                 "https://github.com/team/app.git",
+		"app/vendor/lib":      "https://github.com/team/lib.git",
+		"my tools":            "",
+		"team/api":            "git@github.com:team/api.git",
+		"a/b/c/deep":          "",
+		"node_modules/pkg":    "",
+		"archive/old":         "",
+		"archive/keep/recent": "",
+	}
+	for rel, url := range layout {
+		repo, err := git.PlainInit(filepath.Join(root, rel), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if url != "" {
+			if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{url}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		excludes []string
+		want     []string
+	}{
+		{
+			name:     "unlimited depth",
+			maxDepth: -1,
+			want: []string{
+				"deep " + filepath.Join(root, "a/b/c/deep"),
+				"app https://github.com/team/app.git",
+				"api git@github.com:team/api.git",
+				"my-tools " + filepath.Join(root, "my tools"),
+				"old " + filepath.Join(root, "archive/old"),
+				"pkg " + filepath.Join(root, "node_modules/pkg"),
+				"recent " + filepath.Join(root, "archive/keep/recent"),
+			},
+		},
+		{
+			name:     "max depth",
+			maxDepth: 2,
+			want: []string{
+				"app https://github.com/team/app.git",
+				"api git@github.com:team/api.git",
+				"my-tools " + filepath.Join(root, "my tools"),
+				"old " + filepath.Join(root, "archive/old"),
+				"pkg " + filepath.Join(root, "node_modules/pkg"),
+			},
+		},
+		{
+			name:     "excludes by name and relative path",
+			maxDepth: -1,
+			excludes: []string{"node_modules", "archive/*", "a"},
+			want: []string{
+				"app https://github.com/team/app.git",
+				"api git@github.com:team/api.git",
+				"my-tools " + filepath.Join(root, "my tools"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos, err := DiscoverRepositories(root, tt.maxDepth, tt.excludes, []string{"local"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, repo := range repos {
+				if err := ValidateName(repo.Name); err != nil {
+					t.Errorf("discovered name : %v", err)
+				}
+				if !reflect.DeepEqual(repo.Labels, []string{"local"}) {
+					t.Errorf("labels of %v = %v, want [local]", repo.Name, repo.Labels)
+				}
+				location := repo.Url
+				if location == "" {
+					location = repo.Path
+				}
+				got = append(got, repo.Name+" "+location)
+			}
+			sort.Strings(got)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DiscoverRepositories() =\n%v, want\n%v", got, want)
+			}
+		})
+	}
+}
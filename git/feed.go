@@ -0,0 +1,155 @@
+package git
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Maximum number of entries of a feed, the most recent commits being kept
+const MaxFeedEntries = 200
+
+// Namespace of the entry ids, the url namespace of RFC 4122
+var feedNamespace = [16]byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// Feed is an Atom feed (RFC 4287) of commits
+type Feed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Id      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []FeedLink  `xml:"link,omitempty"`
+	Author  FeedPerson  `xml:"author"`
+	Entries []FeedEntry `xml:"entry"`
+}
+
+type FeedLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type FeedPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+}
+
+type FeedCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type FeedContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type FeedEntry struct {
+	Id        string         `xml:"id"`
+	Title     string         `xml:"title"`
+	Updated   string         `xml:"updated"`
+	Published string         `xml:"published"`
+	Author    FeedPerson     `xml:"author"`
+	Category  []FeedCategory `xml:"category"`
+	Content   FeedContent    `xml:"content"`
+}
+
+// FeedTitle describes the filters of a feed
+func FeedTitle(f Filter) string {
+	title := "git-follow-up commits"
+	var filters []string
+	if len(f.Labels) > 0 {
+		filters = append(filters, "labels "+strings.Join(f.Labels, ", "))
+	}
+	if len(f.Authors) > 0 {
+		filters = append(filters, "authors "+strings.Join(f.Authors, ", "))
+	}
+	if len(filters) > 0 {
+		title += " (" + strings.Join(filters, ", ") + ")"
+	}
+	return title
+}
+
+// feedQuery describes the commits selected by the filter, whatever their dates
+func feedQuery(f Filter) string {
+	query := url.Values{}
+	for _, label := range f.Labels {
+		query.Add("label", label)
+	}
+	for _, author := range f.Authors {
+		query.Add("author", author)
+	}
+	for _, issue := range f.Issues {
+		query.Add("issue", issue)
+	}
+	for _, trailer := range f.Trailers {
+		query.Add("trailer", trailer.Key+"="+trailer.Value)
+	}
+	if f.CoAuthors {
+		query.Set("co-authors", "true")
+	}
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	return query.Encode()
+}
+
+// NewFeed creates a feed of the commits matching the filter, most recent first
+// The feed id is derived from its self url, or from the filter without self url,
+// and the entry ids from the repository name and the commit hash
+func NewFeed(f Filter, selfUrl string, commits []Commit) *Feed {
+	feed := &Feed{
+		Id:     FeedId(selfUrl),
+		Title:  FeedTitle(f),
+		Author: FeedPerson{Name: "git-follow-up"},
+	}
+	if selfUrl != "" {
+		feed.Links = append(feed.Links, FeedLink{Rel: "self", Href: selfUrl})
+	} else {
+		feed.Id = FeedId("git-follow-up/commits?" + feedQuery(f))
+	}
+
+	updated := time.Time{}
+	for i := len(commits) - 1; i >= 0 && len(feed.Entries) < MaxFeedEntries; i-- {
+		c := commits[i]
+		if c.Commit.Committer.When.After(updated) {
+			updated = c.Commit.Committer.When
+		}
+		feed.Entries = append(feed.Entries, FeedEntry{
+			Id:        FeedId(c.Name + "/" + c.Commit.Hash.String()),
+			Title:     "[" + c.Name + "] " + c.Subject(),
+			Updated:   c.Commit.Committer.When.Format(time.RFC3339),
+			Published: c.Commit.Author.When.Format(time.RFC3339),
+			Author:    FeedPerson{Name: c.Commit.Author.Name, Email: c.Commit.Author.Email},
+			Category:  []FeedCategory{{Term: c.Name}},
+			Content:   FeedContent{Type: "text", Body: c.Commit.Message},
+		})
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+	return feed
+}
+
+// Xml returns the feed document
+func (f Feed) Xml() ([]byte, error) {
+	content, err := xml.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+// FeedId returns a stable urn:uuid, the name based uuid (version 5) of the name
+func FeedId(name string) string {
+	hash := sha1.New()
+	hash.Write(feedNamespace[:])
+	hash.Write([]byte(name))
+	uuid := hash.Sum(nil)[:16]
+	uuid[6] = (uuid[6] & 0x0f) | 0x50
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
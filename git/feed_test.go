@@ -0,0 +1,103 @@
+package git
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedId(t *testing.T) {
+	// Reference uuid of the url namespace
+	if got, want := FeedId("http://www.example.com/"), "urn:uuid:fcde3c85-2270-590f-9e7c-ee003d65e0e2"; got != want {
+		t.Errorf("FeedId() = %v, want %v", got, want)
+	}
+	if FeedId("cobra/1111111111111111111111111111111111111111") == FeedId("viper/1111111111111111111111111111111111111111") {
+		t.Errorf("FeedId() is the same for the same commit in different repositories")
+	}
+}
+
+func TestNewFeed(t *testing.T) {
+	commits := []Commit{
+		digestCommit("viper", "1111111111111111111111111111111111111111", "Jean", "2019-06-10", "Fix env <override>\n\nDetails & more"),
+		digestCommit("cobra", "2222222222222222222222222222222222222222", "Marie", "2019-06-11", "Add completion"),
+	}
+	feed := NewFeed(Filter{Labels: []string{"go"}}, "http://localhost:8080/feed.atom?label=go", commits)
+	content, err := feed.Xml()
+	if err != nil {
+		t.Fatalf("Xml() error = %v", err)
+	}
+	if !strings.HasPrefix(string(content), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<feed xmlns="http://www.w3.org/2005/Atom">`) {
+		t.Errorf("Xml() isn't an atom document :\n%s", content)
+	}
+
+	var parsed Feed
+	if err := xml.Unmarshal(content, &parsed); err != nil {
+		t.Fatalf("Xml() isn't valid : %v", err)
+	}
+	if parsed.Id != FeedId("http://localhost:8080/feed.atom?label=go") || parsed.Title != "git-follow-up commits (labels go)" {
+		t.Errorf("feed = %v %q, want the id of the self url and the title of the filter", parsed.Id, parsed.Title)
+	}
+	if parsed.Updated != "2019-06-11T00:00:00Z" {
+		t.Errorf("feed updated = %v, want the date of the last commit", parsed.Updated)
+	}
+	if len(parsed.Entries) != 2 {
+		t.Fatalf("feed has %d entries, want 2", len(parsed.Entries))
+	}
+	first := parsed.Entries[0]
+	if first.Title != "[cobra] Add completion" || first.Author.Name != "Marie" {
+		t.Errorf("first entry = %+v, want the most recent commit", first)
+	}
+	if first.Id != FeedId("cobra/2222222222222222222222222222222222222222") {
+		t.Errorf("entry id = %v, want the id of the repository and hash", first.Id)
+	}
+	if parsed.Entries[1].Content.Body != "Fix env <override>\n\nDetails & more" {
+		t.Errorf("entry content = %q, want the commit message", parsed.Entries[1].Content.Body)
+	}
+}
+
+func TestNewFeedWithoutSelfUrl(t *testing.T) {
+	from := time.Date(2019, time.June, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		filter   Filter
+		other    Filter
+		wantSame bool
+	}{
+		{
+			name:   "labels",
+			filter: Filter{Labels: []string{"go"}},
+			other:  Filter{Labels: []string{"java"}},
+		},
+		{
+			name:   "issues",
+			filter: Filter{Issues: []string{"PROJ-1"}},
+			other:  Filter{},
+		},
+		{
+			name:   "trailers",
+			filter: Filter{Trailers: []Trailer{{Key: "Reviewed-by", Value: "jean"}}},
+			other:  Filter{Trailers: []Trailer{{Key: "Reviewed-by", Value: "marie"}}},
+		},
+		{
+			name:     "dates",
+			filter:   Filter{Labels: []string{"go"}, From: from},
+			other:    Filter{Labels: []string{"go"}, From: from.AddDate(0, 0, 7)},
+			wantSame: true,
+		},
+		{
+			name:     "order",
+			filter:   Filter{Authors: []string{"jean", "marie"}},
+			other:    Filter{Authors: []string{"marie", "jean"}},
+			wantSame: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, other := NewFeed(tt.filter, "", nil).Id, NewFeed(tt.other, "", nil).Id
+			if (id == other) != tt.wantSame {
+				t.Errorf("NewFeed() ids = %v and %v, want same %v", id, other, tt.wantSame)
+			}
+		})
+	}
+}
@@ -5,105 +5,234 @@ import (
 	"github.com/spf13/pflag"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 	"regexp"
+	"strings"
 	"time"
 )
 
 type Filter struct {
-	From    time.Time
+	From time.Time
+	// Exclusive end date, no end date if zero
+	To      time.Time
 	Labels  []string
 	Authors []string
 	Display []string
+	Issues  []string
+	// Whether the author filter also matches Co-authored-by trailers
+	CoAuthors bool
+	Trailers  []Trailer
 }
 
 var DisplayArgs = []string{"repo", "date", "hash", "message", "author"}
 var FromArgs = []string{"ytd", "mtd", "wtd", "yesterday", "today"}
 
-func NewFilter(flags *pflag.FlagSet) (f *Filter) {
-	f = &Filter{}
+// ToArgs are the named end days of the to flag, the day being included
+var ToArgs = []string{"yesterday", "today"}
+
+// NewFilter returns the filter of the flags registered by the commands listing commits
+func NewFilter(flags *pflag.FlagSet) (*Filter, error) {
+	f := &Filter{}
 
 	// Date filter
 	from, err := flags.GetString("from")
 	if err != nil {
-		fmt.Printf("%v\n", err)
+		return nil, err
+	}
+	if err := f.setFrom(from, time.Now()); err != nil {
+		return nil, err
+	}
+
+	to, err := flags.GetString("to")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.setTo(to, time.Now()); err != nil {
+		return nil, err
 	}
-	f.setFrom(from, time.Now())
 
 	// Labels filter
 	labels, err := flags.GetStringSlice("label")
 	if err != nil {
-		fmt.Printf("%v\n", err)
+		return nil, err
 	}
 	f.Labels = append(f.Labels, labels...)
 
 	// Author filter
 	authors, err := flags.GetStringSlice("author")
 	if err != nil {
-		fmt.Printf("%v\n", err)
+		return nil, err
 	}
 	f.Authors = append(f.Authors, authors...)
 
+	coAuthors, err := flags.GetBool("co-authors")
+	if err != nil {
+		return nil, err
+	}
+	f.CoAuthors = coAuthors
+
+	// Trailer filter
+	trailers, err := flags.GetStringSlice("trailer")
+	if err != nil {
+		return nil, err
+	}
+	for _, trailer := range trailers {
+		kv := strings.SplitN(trailer, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("trailer flag not recognized : %s (expected key=value)", trailer)
+		}
+		f.Trailers = append(f.Trailers, Trailer{Key: kv[0], Value: kv[1]})
+	}
+
+	// Issue filter
+	issues, err := flags.GetStringSlice("issue")
+	if err != nil {
+		return nil, err
+	}
+	f.Issues = append(f.Issues, issues...)
+
 	// Display filter
 	if !flags.Changed("display") {
 		f.Display = append(f.Display, DisplayArgs...)
 	} else {
 		displays, err := flags.GetStringSlice("display")
 		if err != nil {
-			fmt.Printf("%v\n", err)
+			return nil, err
 		}
 		f.Display = append(f.Display, displays...)
 	}
 
-	return
+	return f, nil
 }
 
-func (filter *Filter) setFrom(from string, now time.Time) {
-	regexDate, _ := regexp.Compile("([12]\\d{3}-(0[1-9]|1[0-2])-(0[1-9]|[12]\\d|3[01]))")
+var dateRegex = regexp.MustCompile(`^[12]\d{3}-(0[1-9]|1[0-2])-(0[1-9]|[12]\d|3[01])$`)
 
+// ParseFrom returns the start date of a from value : ytd, mtd, wtd, yesterday, today or yyyy-MM-dd
+func ParseFrom(from string, now time.Time) (t time.Time, err error) {
 	currentYear, currentMonth, currentDay := now.Date()
 	currentLocation := now.Location()
 
 	switch {
 	case from == "ytd":
-		filter.From = time.Date(currentYear, time.January, 1, 0, 0, 0, 0, currentLocation)
+		t = time.Date(currentYear, time.January, 1, 0, 0, 0, 0, currentLocation)
 		break
 	case from == "mtd":
-		filter.From = time.Date(currentYear, currentMonth, 1, 0, 0, 0, 0, currentLocation)
+		t = time.Date(currentYear, currentMonth, 1, 0, 0, 0, 0, currentLocation)
 		break
 	case from == "wtd":
 		weekday := now.Weekday()
 		y, m, d := now.AddDate(0, 0, -(int(weekday+6)%7)).Date()
-		filter.From = time.Date(y, m, d, 0, 0, 0, 0, currentLocation)
+		t = time.Date(y, m, d, 0, 0, 0, 0, currentLocation)
 		break
 	case from == "today":
-		filter.From = time.Date(currentYear, currentMonth, currentDay, 0, 0, 0, 0, currentLocation)
+		t = time.Date(currentYear, currentMonth, currentDay, 0, 0, 0, 0, currentLocation)
 		break
 	case from == "yesterday":
-		filter.From = time.Date(currentYear, currentMonth, currentDay-1, 0, 0, 0, 0, currentLocation)
+		t = time.Date(currentYear, currentMonth, currentDay-1, 0, 0, 0, 0, currentLocation)
 		break
-	case regexDate.MatchString(from):
-		filter.From, _ = time.Parse("2006-01-02", from)
+	case dateRegex.MatchString(from):
+		// Days out of the month, such as 2024-02-31, are rejected
+		t, err = time.Parse("2006-01-02", from)
 		break
 	default:
-		fmt.Println("from flag not recognized")
+		err = fmt.Errorf("from flag not recognized")
 		break
 	}
+	return t, err
+}
+
+func (filter *Filter) setFrom(from string, now time.Time) (err error) {
+	filter.From, err = ParseFrom(from, now)
+	return err
+}
+
+// setTo sets the end date, the day of the to value being included
+func (filter *Filter) setTo(to string, now time.Time) error {
+	if to == "" {
+		return nil
+	}
+	t, err := ParseTo(to, now)
+	if err != nil {
+		return err
+	}
+	filter.To = t.AddDate(0, 0, 1)
+	return nil
+}
+
+// ParseTo returns the day of a to value : yesterday, today or yyyy-MM-dd
+// Periods such as mtd have no end day, and aren't recognized.
+func ParseTo(to string, now time.Time) (time.Time, error) {
+	if !Contains(ToArgs, to) && !dateRegex.MatchString(to) {
+		return time.Time{}, fmt.Errorf("to flag not recognized, possible values : %s, yyyy-MM-dd", strings.Join(ToArgs, ", "))
+	}
+	return ParseFrom(to, now)
 }
 
 func (filter Filter) Filter(c *object.Commit) (b bool) {
 
 	b = true
-	author := c.Author.Name + " " + c.Author.Email
+	authors := []string{c.Author.Name + " " + c.Author.Email}
+	if filter.CoAuthors {
+		authors = append(authors, CoAuthors(c.Message)...)
+	}
 
 	switch {
 	// Filter by date
 	case c.Author.When.Before(filter.From):
 		b = false
 		break
+	case !filter.To.IsZero() && !c.Author.When.Before(filter.To):
+		b = false
+		break
 	// Filter by author
-	case filter.Authors != nil && !MatchAny(author, filter.Authors):
+	case filter.Authors != nil && !matchAnyAuthor(authors, filter.Authors):
+		b = false
+		break
+	// Filter by trailers
+	case filter.Trailers != nil && !filter.matchTrailers(ParseTrailers(c.Message)):
 		b = false
 		break
 	}
 
 	return
 }
+
+func matchAnyAuthor(authors []string, filterAuthors []string) bool {
+	for _, author := range authors {
+		if MatchAny(author, filterAuthors) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTrailers returns whether every trailer of the filter is found in trailers.
+// Keys are compared case insensitively and values are matched as substrings.
+func (filter Filter) matchTrailers(trailers []Trailer) bool {
+	for _, expected := range filter.Trailers {
+		found := false
+		for _, value := range TrailerValues(trailers, expected.Key) {
+			if strings.Contains(strings.ToLower(value), strings.ToLower(expected.Value)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterIssues returns whether one of the referenced issues matches the issue filter
+func (filter Filter) FilterIssues(issues []IssueRef) bool {
+	if len(filter.Issues) == 0 {
+		return true
+	}
+	for _, issue := range issues {
+		for _, key := range filter.Issues {
+			if sameIssue(issue.Key, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,7 +1,9 @@
 package git
 
 import (
+	"github.com/spf13/pflag"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -117,10 +119,13 @@ func TestFilter_setFrom(t *testing.T) {
 
 func TestFilter_Filter(t *testing.T) {
 	type fields struct {
-		From    time.Time
-		Labels  []string
-		Authors []string
-		Display []string
+		From      time.Time
+		To        time.Time
+		Labels    []string
+		Authors   []string
+		Display   []string
+		CoAuthors bool
+		Trailers  []Trailer
 	}
 	type args struct {
 		c *object.Commit
@@ -197,16 +202,110 @@ func TestFilter_Filter(t *testing.T) {
 				From: time.Date(2019, time.May, 5, 0, 0, 0, 0, time.UTC),
 			},
 
+			wantB: false,
+		},
+		{
+			name: "filtering by date range, end date excluded",
+			args: args{
+				c: &object.Commit{
+					Author: object.Signature{
+						Name:  "jack",
+						Email: "test@test.te",
+						When:  time.Date(2019, time.May, 6, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			fields: fields{
+				From: time.Date(2019, time.May, 5, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2019, time.May, 6, 0, 0, 0, 0, time.UTC),
+			},
+
+			wantB: false,
+		},
+		{
+			name: "filtering by author, co-author found",
+			args: args{
+				c: &object.Commit{
+					Author: object.Signature{
+						Name:  "jack",
+						Email: "test@test.te",
+						When:  time.Date(2019, time.May, 5, 0, 0, 0, 0, time.UTC),
+					},
+					Message: "Pairing session\n\nCo-authored-by: jean <jean@test.te>",
+				},
+			},
+			fields: fields{
+				Authors:   []string{"jean"},
+				CoAuthors: true,
+			},
+
+			wantB: true,
+		},
+		{
+			name: "filtering by author, co-authors not considered",
+			args: args{
+				c: &object.Commit{
+					Author: object.Signature{
+						Name:  "jack",
+						Email: "test@test.te",
+						When:  time.Date(2019, time.May, 5, 0, 0, 0, 0, time.UTC),
+					},
+					Message: "Pairing session\n\nCo-authored-by: jean <jean@test.te>",
+				},
+			},
+			fields: fields{
+				Authors: []string{"jean"},
+			},
+
+			wantB: false,
+		},
+		{
+			name: "filtering by trailer, matching",
+			args: args{
+				c: &object.Commit{
+					Author: object.Signature{
+						Name:  "jack",
+						Email: "test@test.te",
+						When:  time.Date(2019, time.May, 5, 0, 0, 0, 0, time.UTC),
+					},
+					Message: "Fix login\n\nReviewed-by: Jean <jean@test.te>\nSigned-off-by: jack <test@test.te>",
+				},
+			},
+			fields: fields{
+				Trailers: []Trailer{{Key: "reviewed-by", Value: "jean"}},
+			},
+
+			wantB: true,
+		},
+		{
+			name: "filtering by trailer, not matching",
+			args: args{
+				c: &object.Commit{
+					Author: object.Signature{
+						Name:  "jack",
+						Email: "test@test.te",
+						When:  time.Date(2019, time.May, 5, 0, 0, 0, 0, time.UTC),
+					},
+					Message: "Fix login\n\nSigned-off-by: jack <test@test.te>",
+				},
+			},
+			fields: fields{
+				Trailers: []Trailer{{Key: "Reviewed-by", Value: "jean"}},
+			},
+
 			wantB: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			filter := Filter{
-				From:    tt.fields.From,
-				Labels:  tt.fields.Labels,
-				Authors: tt.fields.Authors,
-				Display: tt.fields.Display,
+				From:      tt.fields.From,
+				To:        tt.fields.To,
+				Labels:    tt.fields.Labels,
+				Authors:   tt.fields.Authors,
+				Display:   tt.fields.Display,
+				CoAuthors: tt.fields.CoAuthors,
+				Trailers:  tt.fields.Trailers,
 			}
 			if gotB := filter.Filter(tt.args.c); gotB != tt.wantB {
 				t.Errorf("Filter.Filter() = %v, want %v", gotB, tt.wantB)
@@ -214,3 +313,62 @@ func TestFilter_Filter(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    Filter
+		wantErr string
+	}{
+		{
+			name: "flags",
+			args: []string{"--from=2019-05-05", "--to=2019-05-06", "--author=jean", "--trailer=Reviewed-by=paul", "--display=hash"},
+			want: Filter{
+				From:     time.Date(2019, time.May, 5, 0, 0, 0, 0, time.UTC),
+				To:       time.Date(2019, time.May, 7, 0, 0, 0, 0, time.UTC),
+				Authors:  []string{"jean"},
+				Trailers: []Trailer{{Key: "Reviewed-by", Value: "paul"}},
+				Display:  []string{"hash"},
+			},
+		},
+		{name: "bad from", args: []string{"--from=last week"}, wantErr: "from flag not recognized"},
+		{name: "bad to", args: []string{"--from=2019-05-05", "--to=tomorrow"}, wantErr: "to flag not recognized, possible values : yesterday, today, yyyy-MM-dd"},
+		{name: "to period", args: []string{"--from=2019-05-05", "--to=mtd"}, wantErr: "to flag not recognized, possible values : yesterday, today, yyyy-MM-dd"},
+		{name: "to invalid date", args: []string{"--from=2019-05-05", "--to=2024-02-31"}, wantErr: `parsing time "2024-02-31": day out of range`},
+		{name: "to date in text", args: []string{"--from=2019-05-05", "--to=x2024-05-01y"}, wantErr: "to flag not recognized, possible values : yesterday, today, yyyy-MM-dd"},
+		{name: "from invalid date", args: []string{"--from=2019-04-31"}, wantErr: `parsing time "2019-04-31": day out of range`},
+		{name: "from date in text", args: []string{"--from=x2019-05-05y"}, wantErr: "from flag not recognized"},
+		{name: "bad trailer", args: []string{"--from=2019-05-05", "--trailer=Reviewed-by"}, wantErr: "trailer flag not recognized : Reviewed-by (expected key=value)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("from", "", "")
+			flags.String("to", "", "")
+			for _, name := range []string{"label", "author", "trailer", "issue", "display"} {
+				flags.StringSlice(name, []string{}, "")
+			}
+			flags.Bool("co-authors", false, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := NewFilter(flags)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("NewFilter() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.From.Equal(tt.want.From) || !got.To.Equal(tt.want.To) ||
+				!reflect.DeepEqual(got.Authors, tt.want.Authors) || !reflect.DeepEqual(got.Trailers, tt.want.Trailers) ||
+				!reflect.DeepEqual(got.Display, tt.want.Display) {
+				t.Errorf("NewFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package git
+
+import (
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	cryptossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	"net"
+	"strings"
+)
+
+// HostKeyChecking configures the verification of ssh host keys, globally or per repository
+type HostKeyChecking struct {
+	// known_hosts file, ~/.ssh/known_hosts by default
+	KnownHosts string `mapstructure:"known_hosts"`
+	// Pinned host key fingerprint, as displayed by ssh-keygen -l (SHA256:...)
+	Fingerprint string `mapstructure:"host_key_fingerprint"`
+	// When disabled, hosts missing from known_hosts are accepted. Enabled by default.
+	StrictHostKeyChecking *bool `mapstructure:"strict_host_key_checking"`
+}
+
+// WithDefaults returns the host key checking, completed by the global defaults
+func (h HostKeyChecking) WithDefaults(defaults HostKeyChecking) HostKeyChecking {
+	if h.KnownHosts == "" {
+		h.KnownHosts = defaults.KnownHosts
+	}
+	if h.Fingerprint == "" {
+		h.Fingerprint = defaults.Fingerprint
+	}
+	if h.StrictHostKeyChecking == nil {
+		h.StrictHostKeyChecking = defaults.StrictHostKeyChecking
+	}
+	return h
+}
+
+func (h HostKeyChecking) strict() bool {
+	return h.StrictHostKeyChecking == nil || *h.StrictHostKeyChecking
+}
+
+// HostKeyCallback verifies host keys against the known_hosts file and the pinned fingerprint
+func (h HostKeyChecking) HostKeyCallback() (cryptossh.HostKeyCallback, error) {
+	knownHosts := h.KnownHosts
+	if knownHosts == "" {
+		knownHosts = "~/.ssh/known_hosts"
+	}
+	knownHosts, err := homedir.Expand(knownHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	var knownHostsCallback cryptossh.HostKeyCallback
+	// A pinned fingerprint is enough to trust a host missing from the default known_hosts
+	if h.KnownHosts != "" || h.Fingerprint == "" {
+		knownHostsCallback, err = ssh.NewKnownHostsCallback(knownHosts)
+		if err != nil && h.strict() {
+			return nil, fmt.Errorf("known_hosts error: %v", err)
+		}
+	}
+
+	return func(hostname string, remote net.Addr, key cryptossh.PublicKey) error {
+		if h.Fingerprint != "" && !matchFingerprint(key, h.Fingerprint) {
+			return fmt.Errorf("host key verification failed for %v : fingerprint %v doesn't match pinned fingerprint %v",
+				hostname, cryptossh.FingerprintSHA256(key), h.Fingerprint)
+		}
+
+		if knownHostsCallback == nil {
+			return nil
+		}
+		err := knownHostsCallback(hostname, remote, key)
+		if keyErr, ok := err.(*knownhosts.KeyError); ok {
+			if len(keyErr.Want) == 0 {
+				if !h.strict() || h.Fingerprint != "" {
+					return nil
+				}
+				return fmt.Errorf("host key verification failed for %v : host not found in %v (fingerprint %v)",
+					hostname, knownHosts, cryptossh.FingerprintSHA256(key))
+			}
+			return fmt.Errorf("host key verification failed for %v : host key %v doesn't match %v",
+				hostname, cryptossh.FingerprintSHA256(key), knownHosts)
+		}
+		if err != nil {
+			return fmt.Errorf("host key verification failed for %v : %v", hostname, err)
+		}
+		return nil
+	}, nil
+}
+
+func matchFingerprint(key cryptossh.PublicKey, fingerprint string) bool {
+	if strings.HasPrefix(fingerprint, "MD5:") {
+		return strings.TrimPrefix(fingerprint, "MD5:") == cryptossh.FingerprintLegacyMD5(key)
+	}
+	return fingerprint == cryptossh.FingerprintSHA256(key) || "SHA256:"+fingerprint == cryptossh.FingerprintSHA256(key)
+}
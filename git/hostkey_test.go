@@ -0,0 +1,130 @@
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	cryptossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newHostKey(t *testing.T) cryptossh.PublicKey {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := cryptossh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hostkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := newHostKey(t)
+	otherKey := newHostKey(t)
+	knownHosts := filepath.Join(dir, "known_hosts")
+	content := knownhosts.Line([]string{"github.com", "140.82.121.3"}, key) + "\n" +
+		knownhosts.Line([]string{"gitlab.com", "172.65.251.78"}, otherKey) + "\n"
+	if err := ioutil.WriteFile(knownHosts, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	no := false
+
+	tests := []struct {
+		name     string
+		checking HostKeyChecking
+		host     string
+		ip       string
+		key      cryptossh.PublicKey
+		// Error of HostKeyCallback, else of the callback, empty if none
+		wantErr string
+	}{
+		{name: "known host", checking: HostKeyChecking{KnownHosts: knownHosts}, host: "github.com", ip: "140.82.121.3", key: key},
+		{
+			name:     "unknown host strict",
+			checking: HostKeyChecking{KnownHosts: knownHosts},
+			host:     "bitbucket.org", ip: "104.192.141.1", key: key,
+			wantErr: "host not found in " + knownHosts,
+		},
+		{
+			name:     "unknown host not strict",
+			checking: HostKeyChecking{KnownHosts: knownHosts, StrictHostKeyChecking: &no},
+			host:     "bitbucket.org", ip: "104.192.141.1", key: key,
+		},
+		{
+			name:     "changed key",
+			checking: HostKeyChecking{KnownHosts: knownHosts},
+			host:     "gitlab.com", ip: "172.65.251.78", key: key,
+			wantErr: "host key " + cryptossh.FingerprintSHA256(key) + " doesn't match " + knownHosts,
+		},
+		{
+			name:     "changed key not strict",
+			checking: HostKeyChecking{KnownHosts: knownHosts, StrictHostKeyChecking: &no},
+			host:     "gitlab.com", ip: "172.65.251.78", key: key,
+			wantErr: "doesn't match",
+		},
+		{
+			name:     "fingerprint match",
+			checking: HostKeyChecking{Fingerprint: cryptossh.FingerprintSHA256(key)},
+			host:     "bitbucket.org", ip: "104.192.141.1", key: key,
+		},
+		{
+			name:     "fingerprint match without prefix",
+			checking: HostKeyChecking{Fingerprint: strings.TrimPrefix(cryptossh.FingerprintSHA256(key), "SHA256:")},
+			host:     "bitbucket.org", ip: "104.192.141.1", key: key,
+		},
+		{
+			name:     "md5 fingerprint match",
+			checking: HostKeyChecking{Fingerprint: "MD5:" + cryptossh.FingerprintLegacyMD5(key)},
+			host:     "bitbucket.org", ip: "104.192.141.1", key: key,
+		},
+		{
+			name:     "fingerprint mismatch",
+			checking: HostKeyChecking{Fingerprint: cryptossh.FingerprintSHA256(otherKey)},
+			host:     "bitbucket.org", ip: "104.192.141.1", key: key,
+			wantErr: "doesn't match pinned fingerprint " + cryptossh.FingerprintSHA256(otherKey),
+		},
+		{
+			name:     "fingerprint mismatch of a known host",
+			checking: HostKeyChecking{KnownHosts: knownHosts, Fingerprint: cryptossh.FingerprintSHA256(otherKey)},
+			host:     "github.com", ip: "140.82.121.3", key: key,
+			wantErr: "doesn't match pinned fingerprint",
+		},
+		{
+			name:     "missing known_hosts strict",
+			checking: HostKeyChecking{KnownHosts: filepath.Join(dir, "missing")},
+			wantErr:  "known_hosts error",
+		},
+		{
+			name:     "missing known_hosts not strict",
+			checking: HostKeyChecking{KnownHosts: filepath.Join(dir, "missing"), StrictHostKeyChecking: &no},
+			host:     "bitbucket.org", ip: "104.192.141.1", key: key,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback, err := tt.checking.HostKeyCallback()
+			if err == nil && tt.key != nil {
+				err = callback(tt.host+":22", &net.TCPAddr{IP: net.ParseIP(tt.ip), Port: 22}, tt.key)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("host key verification error = %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("host key verification error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
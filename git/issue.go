@@ -0,0 +1,141 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Issue patterns used when a repository doesn't define its own issue trackers
+// Project keys start with at least two letters
+var DefaultIssuePatterns = []string{`\b[A-Z]{2}[A-Z0-9]*-\d+\b`, `#\d+\b`}
+
+// Prefixes of the standards and identifiers matched by the default patterns, but not issue keys (UTF-8, SHA-256...)
+var nonIssuePrefixes = []string{"CVE", "ECMA", "HTTP", "IEEE", "ISO", "RFC", "SHA", "TLS", "UTF"}
+
+// IssueTracker maps an issue key pattern to an URL template.
+// The {id} placeholder of the URL is replaced by the issue key, without any leading '#'.
+type IssueTracker struct {
+	Pattern string
+	Url     string
+}
+
+// IssueRef is an issue referenced by a commit message
+type IssueRef struct {
+	Key  string `json:"key"`
+	Link string `json:"link,omitempty"`
+}
+
+type issueMatcher struct {
+	regex *regexp.Regexp
+	url   string
+	// Whether the keys of nonIssuePrefixes are skipped, for the default patterns
+	defaults bool
+}
+
+func (r Repository) issueMatchers() (matchers []issueMatcher, err error) {
+	trackers := r.IssueTrackers
+	defaults := len(trackers) == 0
+	if defaults {
+		for _, pattern := range DefaultIssuePatterns {
+			trackers = append(trackers, IssueTracker{Pattern: pattern})
+		}
+	}
+
+	for _, tracker := range trackers {
+		regex, err := regexp.Compile(tracker.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%v : issue pattern error: %v", r.Name, err)
+		}
+		matchers = append(matchers, issueMatcher{regex: regex, url: tracker.Url, defaults: defaults})
+	}
+	return matchers, nil
+}
+
+func extractIssues(message string, matchers []issueMatcher) (issues []IssueRef) {
+	for _, m := range matchers {
+		for _, key := range m.regex.FindAllString(message, -1) {
+			if containsIssue(issues, key) {
+				continue
+			}
+			if m.defaults && Contains(nonIssuePrefixes, strings.SplitN(key, "-", 2)[0]) {
+				continue
+			}
+			ref := IssueRef{Key: key}
+			if m.url != "" {
+				ref.Link = strings.Replace(m.url, "{id}", strings.TrimPrefix(key, "#"), -1)
+			}
+			issues = append(issues, ref)
+		}
+	}
+	return issues
+}
+
+func containsIssue(issues []IssueRef, key string) bool {
+	for _, issue := range issues {
+		if sameIssue(issue.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameIssue(a string, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "#"), strings.TrimPrefix(b, "#"))
+}
+
+// Issue gathers the commits referencing an issue key
+type Issue struct {
+	IssueRef
+	Commits []Commit
+}
+
+// Repos returns the names of the repositories whose commits reference the issue
+func (i Issue) Repos() (repos []string) {
+	for _, c := range i.Commits {
+		if !Contains(repos, c.Name) {
+			repos = append(repos, c.Name)
+		}
+	}
+	return repos
+}
+
+// Authors returns the names of the authors of the commits referencing the issue
+func (i Issue) Authors() (authors []string) {
+	for _, c := range i.Commits {
+		if !Contains(authors, c.Commit.Author.Name) {
+			authors = append(authors, c.Commit.Author.Name)
+		}
+	}
+	return authors
+}
+
+// GroupByIssue returns the issues referenced by the commits, sorted by key.
+// Issues are identified by their link when available.
+// Otherwise, '#' keys are local to a repository, and are prefixed by its name.
+func GroupByIssue(commits []Commit) (issues []Issue) {
+	index := make(map[string]int)
+	for _, c := range commits {
+		for _, ref := range c.Issues {
+			if ref.Link == "" && strings.HasPrefix(ref.Key, "#") {
+				ref.Key = c.Name + ref.Key
+			}
+			id := ref.Link
+			if id == "" {
+				id = strings.ToUpper(ref.Key)
+			}
+			i, ok := index[id]
+			if !ok {
+				i = len(issues)
+				index[id] = i
+				issues = append(issues, Issue{IssueRef: ref})
+			}
+			issues[i].Commits = append(issues[i].Commits, c)
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].Key < issues[j].Key
+	})
+	return issues
+}
@@ -0,0 +1,58 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRepository_extractIssues(t *testing.T) {
+	tests := []struct {
+		name     string
+		trackers []IssueTracker
+		message  string
+		want     []IssueRef
+	}{
+		{
+			name:    "default patterns",
+			message: "PROJ-123 fix login (#456)\n\nRelated to PROJ-123 and OPS-7",
+			want:    []IssueRef{{Key: "PROJ-123"}, {Key: "OPS-7"}, {Key: "#456"}},
+		},
+		{
+			name:    "standards",
+			message: "Read UTF-8 files, check SHA-256 sums and ISO-8601 dates\n\nFixes CVE-2021-44228, see RFC-3339 and A1-2",
+			want:    nil,
+		},
+		{
+			name: "configured tracker for a standard",
+			trackers: []IssueTracker{
+				{Pattern: `\bCVE-\d+-\d+\b`, Url: "https://nvd.nist.gov/vuln/detail/{id}"},
+			},
+			message: "Fixes CVE-2021-44228",
+			want:    []IssueRef{{Key: "CVE-2021-44228", Link: "https://nvd.nist.gov/vuln/detail/CVE-2021-44228"}},
+		},
+		{
+			name: "configured tracker",
+			trackers: []IssueTracker{
+				{Pattern: `#\d+`, Url: "https://github.com/acme/foo/issues/{id}"},
+			},
+			message: "Fix login (#456), PROJ-123",
+			want:    []IssueRef{{Key: "#456", Link: "https://github.com/acme/foo/issues/456"}},
+		},
+		{
+			name:    "no issue",
+			message: "Update README",
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchers, err := Repository{IssueTrackers: tt.trackers}.issueMatchers()
+			if err != nil {
+				t.Fatalf("issueMatchers() error = %v", err)
+			}
+			if got := extractIssues(tt.message, matchers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractIssues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
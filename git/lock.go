@@ -0,0 +1,210 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Lock is a lock file created exclusively, containing the PID and host name of its holder
+type Lock struct {
+	path string
+	// PID of a crashed process whose lock file was replaced, 0 otherwise
+	StalePID int
+}
+
+// LockedError is returned when a lock file is held by another process
+type LockedError struct {
+	Path string
+	PID  int
+	Host string
+	// Modification time of the lock file
+	modified time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%v is locked by PID %d", e.Path, e.PID)
+}
+
+// A lock file without PID is being written, unless it is older than this
+const lockWriteDelay = time.Minute
+
+// TryLock creates the lock file, failing with a *LockedError if it is held by a running process
+// Lock files left by crashed processes of this host are replaced
+func TryLock(path string) (*Lock, error) {
+	lock, err := createLock(path)
+	lockedErr, locked := err.(*LockedError)
+	if !locked || !lockedErr.stale() {
+		return lock, err
+	}
+	return replaceStaleLock(path)
+}
+
+// replaceStaleLock replaces a stale lock file, once no other process is replacing it.
+// The replacing processes are serialized by a lock on a guard file, released by the system if they crash,
+// so that none of them removes the lock file another one has just created.
+func replaceStaleLock(path string) (*Lock, error) {
+	guard, err := os.OpenFile(path+".guard", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer guard.Close()
+	if err := lockFile(guard); err != nil {
+		return nil, fmt.Errorf("%v : %v", guard.Name(), err)
+	}
+	defer unlockFile(guard)
+
+	// The lock file may have been replaced or released meanwhile
+	lock, err := createLock(path)
+	lockedErr, locked := err.(*LockedError)
+	if !locked || !lockedErr.stale() {
+		return lock, err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	lock, err = createLock(path)
+	if lock != nil {
+		lock.StalePID = lockedErr.PID
+	}
+	return lock, err
+}
+
+func createLock(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return nil, readLock(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	_, err = fmt.Fprintf(f, "%d\n%s\n", os.Getpid(), host)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return &Lock{path: path}, nil
+}
+
+// AcquireLock waits up to timeout for the lock file to be released, calling onWait once when it has to wait.
+// The wait is aborted with the error of ctx once it is done.
+func AcquireLock(ctx context.Context, path string, timeout time.Duration, onWait func(err *LockedError)) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lock, err := TryLock(path)
+		lockedErr, locked := err.(*LockedError)
+		if !locked || time.Now().After(deadline) {
+			return lock, err
+		}
+		if !waiting && onWait != nil {
+			onWait(lockedErr)
+		}
+		waiting = true
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// Release removes the lock file
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	return os.Remove(l.path)
+}
+
+// readLock reads the holder of a lock file, its PID being 0 while being written
+func readLock(path string) *LockedError {
+	lockedErr := &LockedError{Path: path, modified: time.Now()}
+	if info, err := os.Stat(path); err == nil {
+		lockedErr.modified = info.ModTime()
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return lockedErr
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	lockedErr.PID, _ = strconv.Atoi(strings.TrimSpace(lines[0]))
+	if len(lines) > 1 {
+		lockedErr.Host = strings.TrimSpace(lines[1])
+	}
+	return lockedErr
+}
+
+// stale tells whether the lock holder isn't running anymore
+// Processes of other hosts sharing the directory can't be checked, and are considered running
+func (e *LockedError) stale() bool {
+	switch {
+	case e.PID == 0:
+		// Lock file being written by its holder, unless it crashed meanwhile
+		return time.Since(e.modified) > lockWriteDelay
+	case e.Host != "":
+		if host, _ := os.Hostname(); host != e.Host {
+			return false
+		}
+	}
+	return !processRunning(e.PID)
+}
+
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Finding a process on windows opens it, failing if it doesn't exist
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// Directory of the lock files, next to the mirrors. Repository names can't start with a dot.
+const locksDir = ".locks"
+
+// lockPath returns the lock file of the mirror, out of the mirrors directory so that no mirror is named after it
+func (r Repository) lockPath() string {
+	mirror := filepath.Clean(r.LocalPath)
+	return filepath.Join(filepath.Dir(mirror), locksDir, filepath.Base(mirror)+".lock")
+}
+
+// LockSync locks the mirror of the repository against the syncs of other processes, waiting up to timeout
+// or until ctx is done
+// Local clones are used in place, and aren't locked
+func (r Repository) LockSync(ctx context.Context, timeout time.Duration, onWait func(pid int)) (*Lock, error) {
+	if r.IsLocal() {
+		return nil, nil
+	}
+	// The lock is created before the first clone of the mirror
+	path := r.lockPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	lock, err := AcquireLock(ctx, path, timeout, func(err *LockedError) {
+		if onWait != nil {
+			onWait(err.PID)
+		}
+	})
+	if lockedErr, ok := err.(*LockedError); ok {
+		return nil, fmt.Errorf("repo %v is being synced by PID %d", r.Name, lockedErr.PID)
+	}
+	return lock, err
+}
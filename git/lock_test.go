@@ -0,0 +1,216 @@
+package git
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sync.lock")
+
+	lock, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+
+	_, err = TryLock(path)
+	lockedErr, ok := err.(*LockedError)
+	if !ok {
+		t.Fatalf("TryLock() error = %v, want a *LockedError", err)
+	}
+	if lockedErr.PID != os.Getpid() {
+		t.Errorf("TryLock() PID = %d, want %d", lockedErr.PID, os.Getpid())
+	}
+
+	waited := false
+	if _, err := AcquireLock(context.Background(), path, 300*time.Millisecond, func(*LockedError) { waited = true }); err == nil {
+		t.Errorf("AcquireLock() succeeded on a held lock")
+	}
+	if !waited {
+		t.Errorf("AcquireLock() didn't report waiting")
+	}
+
+	// Cancelled waits return before the timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	started := time.Now()
+	if _, err := AcquireLock(ctx, path, time.Minute, nil); err != context.Canceled {
+		t.Errorf("AcquireLock() error = %v, want %v", err, context.Canceled)
+	}
+	if waited := time.Since(started); waited > 5*time.Second {
+		t.Errorf("AcquireLock() returned after %v, want once cancelled", waited)
+	}
+
+	held := lock
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		_ = held.Release()
+	}()
+	lock, err = AcquireLock(context.Background(), path, 5*time.Second, nil)
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Errorf("Release() error = %v", err)
+	}
+}
+
+func TestTryLock_stale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	host, _ := os.Hostname()
+
+	tests := []struct {
+		name      string
+		content   string
+		age       time.Duration
+		wantStale bool
+	}{
+		{name: "crashed process", content: "99999999\n" + host + "\n", wantStale: true},
+		{name: "running process", content: strconv.Itoa(os.Getpid()) + "\n" + host + "\n", wantStale: false},
+		{name: "other host", content: "99999999\nother-host\n", wantStale: false},
+		{name: "being written", content: "", wantStale: false},
+		{name: "empty", content: "", age: time.Hour, wantStale: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "repo.lock")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(path)
+			modified := time.Now().Add(-tt.age)
+			if err := os.Chtimes(path, modified, modified); err != nil {
+				t.Fatal(err)
+			}
+
+			lock, err := TryLock(path)
+			if tt.wantStale {
+				if err != nil {
+					t.Fatalf("TryLock() error = %v, want a replaced stale lock", err)
+				}
+				if lock.StalePID == 0 && tt.content != "" {
+					t.Errorf("TryLock() StalePID = 0, want the PID of the crashed process")
+				}
+			} else if _, ok := err.(*LockedError); !ok {
+				t.Errorf("TryLock() error = %v, want a *LockedError", err)
+			}
+		})
+	}
+}
+
+func TestTryLock_staleContention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	host, _ := os.Hostname()
+	path := filepath.Join(dir, "repo.lock")
+	writeStale := func() {
+		if err := ioutil.WriteFile(path, []byte("99999999\n"+host+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A process having seen the stale lock before another one replaced it mustn't remove the new lock
+	writeStale()
+	lock, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	if _, err := replaceStaleLock(path); err == nil {
+		t.Fatalf("replaceStaleLock() replaced a held lock")
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v, the lock file was removed by another process", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		writeStale()
+
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		locks := make([]*Lock, 8)
+		errs := make([]error, len(locks))
+		for g := range locks {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				<-start
+				locks[g], errs[g] = TryLock(path)
+			}(g)
+		}
+		close(start)
+		wg.Wait()
+
+		acquired := 0
+		for g, lock := range locks {
+			if lock != nil {
+				acquired++
+				continue
+			}
+			if _, ok := errs[g].(*LockedError); !ok {
+				t.Fatalf("TryLock() error = %v, want a *LockedError", errs[g])
+			}
+		}
+		if acquired != 1 {
+			t.Fatalf("TryLock() acquired the stale lock %d times, want once", acquired)
+		}
+		for _, lock := range locks {
+			_ = lock.Release()
+		}
+	}
+}
+
+func TestLockSync(t *testing.T) {
+	gitPath, err := ioutil.TempDir("", "git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gitPath)
+
+	// The mirrors of foo.lock and foo.lock.guard are where the lock files of foo used to be
+	for _, name := range []string{"foo.lock", "foo.lock.guard"} {
+		if err := os.MkdirAll(filepath.Join(gitPath, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var locks []*Lock
+	for _, name := range []string{"foo", "foo.lock", "foo.lock.guard"} {
+		repo := Repository{Name: name, Url: "https://github.com/acme/" + name + ".git", LocalPath: filepath.Join(gitPath, name)}
+		lock, err := repo.LockSync(context.Background(), time.Second, nil)
+		if err != nil {
+			t.Fatalf("LockSync() of %v error = %v", name, err)
+		}
+		if want := filepath.Join(gitPath, locksDir, name+".lock"); lock.path != want {
+			t.Errorf("lock of %v = %v, want %v", name, lock.path, want)
+		}
+		locks = append(locks, lock)
+	}
+	for _, lock := range locks {
+		if err := lock.Release(); err != nil {
+			t.Errorf("Release() error = %v", err)
+		}
+	}
+
+	// Local clones aren't locked
+	lock, err := Repository{Name: "local", Path: gitPath, LocalPath: gitPath}.LockSync(context.Background(), time.Second, nil)
+	if lock != nil || err != nil {
+		t.Errorf("LockSync() of a local clone = %v, %v, want no lock", lock, err)
+	}
+}
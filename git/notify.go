@@ -0,0 +1,347 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var NotificationTypes = []string{"webhook", "slack", "mattermost", "email"}
+
+// Notification sends the new commits matching all of its rules to its targets
+type Notification struct {
+	Name string
+	// Labels the repository must have
+	Labels []string
+	// Authors, matched partially against the name and email of the commit author
+	Authors []string
+	// Changed paths, as glob patterns or directories
+	Paths []string
+	// Regular expression matching the commit message
+	Message string
+	Targets []NotificationTarget
+}
+
+// NotificationTarget is a chat webhook, a generic JSON webhook, or mail recipients
+type NotificationTarget struct {
+	Type string
+	// Url of the webhook, or its source : file:<path>, env:<VAR> or cmd:<command>
+	Url string
+	// Recipients of the email
+	To []string
+	// Subject template of the email
+	Subject string
+	// Body template, rendering NotificationData
+	Template string
+}
+
+// NotificationData is rendered by the templates of the targets
+type NotificationData struct {
+	Notification string
+	Commits      []Commit
+}
+
+const defaultNotificationTemplate = `{{len .Commits}} new commit{{if gt (len .Commits) 1}}s{{end}} ({{.Notification}})
+{{range .Commits}}[{{.Name}}] {{.ShortHash}} {{.Subject}} - {{.Commit.Author.Name}}
+{{end}}`
+
+const defaultNotificationSubject = `[git-follow-up] {{len .Commits}} new commit{{if gt (len .Commits) 1}}s{{end}} ({{.Notification}})`
+
+// Validate checks the rules, templates and targets of the notification
+func (n Notification) Validate() error {
+	if _, err := regexp.Compile(n.Message); err != nil {
+		return fmt.Errorf("message: %v", err)
+	}
+	for _, pattern := range n.Paths {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("path %q: %v", pattern, err)
+		}
+	}
+	if len(n.Targets) == 0 {
+		return fmt.Errorf("no target")
+	}
+	for _, target := range n.Targets {
+		if err := target.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the type, destination and templates of the target
+func (t NotificationTarget) Validate() error {
+	if !Contains(NotificationTypes, t.Type) {
+		return fmt.Errorf("type %q not recognized, possible values : %v", t.Type, strings.Join(NotificationTypes, ", "))
+	}
+	if t.Type == "email" && len(t.To) == 0 {
+		return fmt.Errorf("%v target: to is required", t.Type)
+	}
+	if t.Type != "email" && t.Url == "" {
+		return fmt.Errorf("%v target: url is required", t.Type)
+	}
+	for _, text := range []string{t.Subject, t.Template} {
+		if _, err := template.New("").Parse(text); err != nil {
+			return fmt.Errorf("%v target: %v", t.Type, err)
+		}
+	}
+	return nil
+}
+
+// Matches tells whether a commit of the repository matches all the rules of the notification
+func (n Notification) Matches(repo Repository, c Commit) (bool, error) {
+	if !ContainsAll(repo.Labels, n.Labels) {
+		return false, nil
+	}
+	if len(n.Authors) > 0 {
+		var authors []string
+		for _, author := range n.Authors {
+			authors = append(authors, strings.ToLower(author))
+		}
+		if !MatchAny(c.Commit.Author.Name+" "+c.Commit.Author.Email, authors) {
+			return false, nil
+		}
+	}
+	if n.Message != "" {
+		message, err := regexp.Compile(n.Message)
+		if err != nil {
+			return false, err
+		}
+		if !message.MatchString(c.Commit.Message) {
+			return false, nil
+		}
+	}
+	if len(n.Paths) > 0 {
+		files, err := c.Files()
+		if err != nil {
+			return false, err
+		}
+		return matchAnyPath(files, n.Paths), nil
+	}
+	return true, nil
+}
+
+// matchAnyPath tells whether a file matches a glob pattern, or is inside a directory
+func matchAnyPath(files []string, patterns []string) bool {
+	for _, file := range files {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, file); matched {
+				return true
+			}
+			if strings.HasPrefix(file, strings.TrimSuffix(pattern, "/")+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Notifier sends notifications to their targets
+type Notifier struct {
+	SMTP   SMTP
+	Client *http.Client
+}
+
+// Notify sends the commits to every target of the notification, returning the errors of the failed targets
+func (n Notifier) Notify(notification Notification, commits []Commit) (errors []error) {
+	data := NotificationData{Notification: notification.Name, Commits: commits}
+	for _, target := range notification.Targets {
+		if err := n.send(target, data); err != nil {
+			errors = append(errors, fmt.Errorf("notification %v : %v target: %v", notification.Name, target.Type, err))
+		}
+	}
+	return errors
+}
+
+func (n Notifier) send(target NotificationTarget, data NotificationData) error {
+	var body []byte
+	var err error
+	switch {
+	case target.Template != "":
+		body, err = render(target.Template, data)
+	case target.Type == "webhook":
+		body, err = json.Marshal(newNotificationPayload(data))
+	default:
+		body, err = render(defaultNotificationTemplate, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch target.Type {
+	case "email":
+		subject := target.Subject
+		if subject == "" {
+			subject = defaultNotificationSubject
+		}
+		renderedSubject, err := render(subject, data)
+		if err != nil {
+			return err
+		}
+		return n.SMTP.Send(target.To, strings.TrimSpace(string(renderedSubject)), "text/plain", string(body))
+	case "slack", "mattermost":
+		payload, err := json.Marshal(map[string]string{"text": string(body), "username": "git-follow-up"})
+		if err != nil {
+			return err
+		}
+		return n.post(target.Url, payload)
+	default:
+		return n.post(target.Url, body)
+	}
+}
+
+func (n Notifier) post(urlSource string, body []byte) error {
+	url := urlSource
+	if strings.HasPrefix(url, "file:") || strings.HasPrefix(url, "env:") || strings.HasPrefix(url, "cmd:") {
+		var err error
+		if url, err = readSecret(urlSource); err != nil {
+			return fmt.Errorf("url: %v", err)
+		}
+	}
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("%v", response.Status)
+	}
+	return nil
+}
+
+func render(text string, data interface{}) ([]byte, error) {
+	tmpl, err := template.New("").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+type notificationPayload struct {
+	Notification string          `json:"notification"`
+	Commits      []commitPayload `json:"commits"`
+}
+
+type commitPayload struct {
+	Repository string    `json:"repository"`
+	Hash       string    `json:"hash"`
+	Date       time.Time `json:"date"`
+	Author     string    `json:"author"`
+	Email      string    `json:"email"`
+	Subject    string    `json:"subject"`
+	Message    string    `json:"message"`
+}
+
+func newNotificationPayload(data NotificationData) notificationPayload {
+	payload := notificationPayload{Notification: data.Notification, Commits: []commitPayload{}}
+	for _, c := range data.Commits {
+		payload.Commits = append(payload.Commits, commitPayload{
+			Repository: c.Name,
+			Hash:       c.Commit.Hash.String(),
+			Date:       c.Commit.Author.When,
+			Author:     c.Commit.Author.Name,
+			Email:      c.Commit.Author.Email,
+			Subject:    c.Subject(),
+			Message:    c.Commit.Message,
+		})
+	}
+	return payload
+}
+
+// Heads returns the commit hash of each branch of the local copy, nil if it isn't cloned yet
+func (r Repository) Heads() (map[string]plumbing.Hash, error) {
+	gitRepo, err := git.PlainOpen(r.LocalPath)
+	if err == git.ErrRepositoryNotExists {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+	branches, err := gitRepo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+	heads := make(map[string]plumbing.Hash)
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		heads[ref.Name().Short()] = ref.Hash()
+		return nil
+	})
+	return heads, err
+}
+
+// NewCommits returns the commits of the branches not reachable from the previous heads, sorted by date
+func (r Repository) NewCommits(previous map[string]plumbing.Hash) ([]Commit, error) {
+	heads, err := r.Heads()
+	if err != nil {
+		return nil, err
+	}
+	gitRepo, err := git.PlainOpen(r.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+	issueMatchers, err := r.issueMatchers()
+	if err != nil {
+		return nil, err
+	}
+
+	// Commits known before the sync, whose history is walked once
+	known := make(map[plumbing.Hash]bool)
+	for _, hash := range previous {
+		commit, err := gitRepo.CommitObject(hash)
+		if err != nil {
+			// History rewritten, and no longer stored
+			continue
+		}
+		err = object.NewCommitPreorderIter(commit, known, nil).ForEach(func(c *object.Commit) error {
+			known[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%v : %v", r.Name, err)
+		}
+	}
+
+	var commits []Commit
+	for branch, hash := range heads {
+		if previous[branch] == hash {
+			continue
+		}
+		commit, err := gitRepo.CommitObject(hash)
+		if err != nil {
+			return nil, fmt.Errorf("%v : %v", r.Name, err)
+		}
+		err = object.NewCommitPreorderIter(commit, known, nil).ForEach(func(c *object.Commit) error {
+			known[c.Hash] = true
+			newCommit := NewCommit(c, gitRepo, r.Name)
+			newCommit.Issues = extractIssues(c.Message, issueMatchers)
+			commits = append(commits, *newCommit)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%v : %v", r.Name, err)
+		}
+	}
+
+	sort.Slice(commits, func(i, j int) bool {
+		return commits[i].Commit.Author.When.Before(commits[j].Commit.Author.When)
+	})
+	return commits, nil
+}
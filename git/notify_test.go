@@ -0,0 +1,130 @@
+package git
+
+import (
+	"encoding/json"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotification_Matches(t *testing.T) {
+	repo := Repository{Name: "api", Labels: []string{"prod", "go"}}
+	commit := Commit{
+		Name: "api",
+		Commit: &object.Commit{
+			Author:  object.Signature{Name: "Jean Dupont", Email: "jean@example.com"},
+			Message: "Hotfix the rate limiter\n\nFixes #12",
+		},
+	}
+	tests := []struct {
+		name         string
+		notification Notification
+		want         bool
+	}{
+		{name: "no rule", notification: Notification{}, want: true},
+		{name: "labels", notification: Notification{Labels: []string{"prod"}}, want: true},
+		{name: "missing label", notification: Notification{Labels: []string{"prod", "java"}}, want: false},
+		{name: "author", notification: Notification{Authors: []string{"Jean"}}, want: true},
+		{name: "author email", notification: Notification{Authors: []string{"bob", "@example.com"}}, want: true},
+		{name: "other author", notification: Notification{Authors: []string{"bob"}}, want: false},
+		{name: "message", notification: Notification{Message: "(?i)^hotfix"}, want: true},
+		{name: "other message", notification: Notification{Message: "^revert"}, want: false},
+		{name: "all rules", notification: Notification{Labels: []string{"prod"}, Authors: []string{"jean"}, Message: "#12"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.notification.Matches(repo, commit)
+			if err != nil {
+				t.Fatalf("Matches() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchAnyPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		patterns []string
+		want     bool
+	}{
+		{name: "directory", files: []string{"README.md", "config/prod/app.yaml"}, patterns: []string{"config/prod"}, want: true},
+		{name: "directory with slash", files: []string{"config/prod/app.yaml"}, patterns: []string{"config/prod/"}, want: true},
+		{name: "glob", files: []string{"config/prod/app.yaml"}, patterns: []string{"config/*/*.yaml"}, want: true},
+		{name: "other directory", files: []string{"config/production.yaml"}, patterns: []string{"config/prod"}, want: false},
+		{name: "no file", files: nil, patterns: []string{"config/prod"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchAnyPath(tt.files, tt.patterns); got != tt.want {
+				t.Errorf("matchAnyPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	commits := []Commit{{
+		Name: "api",
+		Commit: &object.Commit{
+			Author:  object.Signature{Name: "Jean Dupont", Email: "jean@example.com"},
+			Message: "Hotfix the rate limiter",
+		},
+	}}
+	notification := Notification{
+		Name: "prod",
+		Targets: []NotificationTarget{
+			{Type: "slack", Url: server.URL + "/slack"},
+			{Type: "webhook", Url: server.URL + "/webhook"},
+			{Type: "mattermost", Url: server.URL + "/mattermost", Template: "{{range .Commits}}{{.Subject}}{{end}}"},
+			{Type: "webhook", Url: server.URL + "/fail"},
+		},
+	}
+	errors := Notifier{Client: server.Client()}.Notify(notification, commits)
+	if len(errors) != 1 || !strings.Contains(errors[0].Error(), "500") {
+		t.Errorf("Notify() errors = %v, want the failure of the last target", errors)
+	}
+	if len(bodies) != 4 {
+		t.Fatalf("Notify() sent %d requests, want 4", len(bodies))
+	}
+
+	var slack map[string]string
+	if err := json.Unmarshal([]byte(bodies[0]), &slack); err != nil {
+		t.Fatal(err)
+	}
+	if want := "1 new commit (prod)\n[api] 00000000 Hotfix the rate limiter - Jean Dupont\n"; slack["text"] != want {
+		t.Errorf("slack text = %q, want %q", slack["text"], want)
+	}
+
+	var payload notificationPayload
+	if err := json.Unmarshal([]byte(bodies[1]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Notification != "prod" || len(payload.Commits) != 1 || payload.Commits[0].Email != "jean@example.com" {
+		t.Errorf("webhook payload = %+v", payload)
+	}
+
+	var mattermost map[string]string
+	if err := json.Unmarshal([]byte(bodies[2]), &mattermost); err != nil {
+		t.Fatal(err)
+	}
+	if mattermost["text"] != "Hotfix the rate limiter" {
+		t.Errorf("mattermost text = %q, want the rendered template", mattermost["text"])
+	}
+}
@@ -0,0 +1,217 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ProviderArgs = []string{"github", "gitlab", "gitea"}
+
+var DefaultProviderUrls = map[string]string{
+	"github": "https://api.github.com",
+	"gitlab": "https://gitlab.com",
+	"gitea":  "https://gitea.com",
+}
+
+// RemoteRepository is a repository listed by the API of a hosting provider
+type RemoteRepository struct {
+	Name     string
+	CloneUrl string
+	SshUrl   string
+	Topics   []string
+	Archived bool
+}
+
+// Provider lists the repositories of an organization (GitHub, Gitea) or group (GitLab)
+type Provider struct {
+	Kind    string
+	BaseUrl string
+	Token   string
+	Client  *http.Client
+}
+
+// NewProvider returns a provider of the given kind, the base url defaulting to the public instance.
+// The token source is file:<path>, env:<VAR> or cmd:<command>, no token being sent if empty.
+func NewProvider(kind string, baseUrl string, tokenSource string) (p *Provider, err error) {
+	if _, ok := DefaultProviderUrls[kind]; !ok {
+		return nil, fmt.Errorf("provider not recognized : %v (%v)", kind, strings.Join(ProviderArgs, ", "))
+	}
+	if baseUrl == "" {
+		baseUrl = DefaultProviderUrls[kind]
+	}
+
+	p = &Provider{
+		Kind:    kind,
+		BaseUrl: strings.TrimRight(baseUrl, "/"),
+		Client:  http.DefaultClient,
+	}
+	if tokenSource != "" {
+		p.Token, err = readSecret(tokenSource)
+		if err != nil {
+			return nil, fmt.Errorf("token error: %v", err)
+		}
+	}
+	return p, nil
+}
+
+// ListRepositories pages through the repositories of owner
+func (p Provider) ListRepositories(owner string) (repos []RemoteRepository, err error) {
+	const perPage = 50
+	for page := 1; ; page++ {
+		var pageRepos []RemoteRepository
+		switch p.Kind {
+		case "github":
+			pageRepos, err = p.listGithub(owner, page, perPage)
+		case "gitlab":
+			pageRepos, err = p.listGitlab(owner, page, perPage)
+		case "gitea":
+			pageRepos, err = p.listGitea(owner, page, perPage)
+		}
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, pageRepos...)
+		if len(pageRepos) < perPage {
+			return repos, nil
+		}
+	}
+}
+
+// OwnerPath returns the path of owner in the urls of its repositories.
+// GitLab groups, given by full path or numeric id, are resolved to their full path, subgroups included.
+func (p Provider) OwnerPath(owner string) (string, error) {
+	if p.Kind != "gitlab" {
+		return owner, nil
+	}
+	var group struct {
+		FullPath string `json:"full_path"`
+	}
+	if err := p.get("/api/v4/groups/"+url.PathEscape(owner), url.Values{}, &group); err != nil {
+		return "", err
+	}
+	if group.FullPath == "" {
+		return "", fmt.Errorf("%v api error: group %v has no full path", p.Kind, owner)
+	}
+	return group.FullPath, nil
+}
+
+func (p Provider) get(path string, query url.Values, result interface{}) error {
+	req, err := http.NewRequest("GET", p.BaseUrl+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.Token != "" {
+		switch p.Kind {
+		case "gitlab":
+			req.Header.Set("PRIVATE-TOKEN", p.Token)
+		default:
+			req.Header.Set("Authorization", "token "+p.Token)
+		}
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%v api error: %v", p.Kind, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v api error: GET %v : %v", p.Kind, req.URL, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("%v api error: GET %v : %v", p.Kind, req.URL, err)
+	}
+	return nil
+}
+
+func pageQuery(page int, perPageKey string, perPage int) url.Values {
+	return url.Values{
+		"page":     []string{strconv.Itoa(page)},
+		perPageKey: []string{strconv.Itoa(perPage)},
+	}
+}
+
+func (p Provider) listGithub(org string, page int, perPage int) (repos []RemoteRepository, err error) {
+	var result []struct {
+		Name     string   `json:"name"`
+		CloneUrl string   `json:"clone_url"`
+		SshUrl   string   `json:"ssh_url"`
+		Topics   []string `json:"topics"`
+		Archived bool     `json:"archived"`
+	}
+	if err := p.get("/orgs/"+url.PathEscape(org)+"/repos", pageQuery(page, "per_page", perPage), &result); err != nil {
+		return nil, err
+	}
+	for _, r := range result {
+		repos = append(repos, RemoteRepository(r))
+	}
+	return repos, nil
+}
+
+func (p Provider) listGitlab(group string, page int, perPage int) (repos []RemoteRepository, err error) {
+	var result []struct {
+		Path     string   `json:"path"`
+		CloneUrl string   `json:"http_url_to_repo"`
+		SshUrl   string   `json:"ssh_url_to_repo"`
+		Topics   []string `json:"topics"`
+		TagList  []string `json:"tag_list"`
+		Archived bool     `json:"archived"`
+	}
+	query := pageQuery(page, "per_page", perPage)
+	query.Set("include_subgroups", "true")
+	if err := p.get("/api/v4/groups/"+url.PathEscape(group)+"/projects", query, &result); err != nil {
+		return nil, err
+	}
+	for _, r := range result {
+		// tag_list was renamed topics in GitLab 14.0
+		topics := r.Topics
+		if len(topics) == 0 {
+			topics = r.TagList
+		}
+		repos = append(repos, RemoteRepository{
+			Name:     r.Path,
+			CloneUrl: r.CloneUrl,
+			SshUrl:   r.SshUrl,
+			Topics:   topics,
+			Archived: r.Archived,
+		})
+	}
+	return repos, nil
+}
+
+func (p Provider) listGitea(org string, page int, perPage int) (repos []RemoteRepository, err error) {
+	var result []struct {
+		Name     string   `json:"name"`
+		CloneUrl string   `json:"clone_url"`
+		SshUrl   string   `json:"ssh_url"`
+		Topics   []string `json:"topics"`
+		Archived bool     `json:"archived"`
+	}
+	if err := p.get("/api/v1/orgs/"+url.PathEscape(org)+"/repos", pageQuery(page, "limit", perPage), &result); err != nil {
+		return nil, err
+	}
+	for _, r := range result {
+		repos = append(repos, RemoteRepository(r))
+	}
+	return repos, nil
+}
+
+var scpUrlRegex = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.*)$`)
+
+// NormalizeUrl reduces a clone url to host/path, so that ssh and https urls of a repository can be compared
+func NormalizeUrl(rawUrl string) string {
+	normalized := rawUrl
+	if u, err := url.Parse(rawUrl); err == nil && u.Scheme != "" && u.Host != "" {
+		normalized = u.Hostname() + u.Path
+	} else if matches := scpUrlRegex.FindStringSubmatch(rawUrl); matches != nil {
+		normalized = matches[1] + "/" + matches[2]
+	}
+	normalized = strings.TrimSuffix(strings.TrimSuffix(normalized, "/"), ".git")
+	return strings.ToLower(strings.Replace(normalized, "//", "/", -1))
+}
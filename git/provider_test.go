@@ -0,0 +1,104 @@
+package git
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestProvider_ListRepositories(t *testing.T) {
+	// 60 repositories over 2 pages of 50
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/acme/repos" || r.Header.Get("Authorization") != "token secret" {
+			http.NotFound(w, r)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		count := 50
+		if page == 2 {
+			count = 10
+		} else if page > 2 {
+			count = 0
+		}
+		fmt.Fprint(w, "[")
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"name":"repo-%d-%d","clone_url":"https://github.com/acme/repo.git","topics":["go"],"archived":%v}`, page, i, i == 0)
+		}
+		fmt.Fprint(w, "]")
+	}))
+	defer server.Close()
+
+	provider := &Provider{Kind: "github", BaseUrl: server.URL, Token: "secret", Client: server.Client()}
+	repos, err := provider.ListRepositories("acme")
+	if err != nil {
+		t.Fatalf("ListRepositories() error = %v", err)
+	}
+	if len(repos) != 60 {
+		t.Fatalf("ListRepositories() returned %d repositories, want 60", len(repos))
+	}
+	want := RemoteRepository{Name: "repo-2-0", CloneUrl: "https://github.com/acme/repo.git", Topics: []string{"go"}, Archived: true}
+	if !reflect.DeepEqual(repos[50], want) {
+		t.Errorf("ListRepositories()[50] = %v, want %v", repos[50], want)
+	}
+}
+
+func TestProvider_OwnerPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/groups/42", "/api/v4/groups/platform%2Fsub":
+			fmt.Fprint(w, `{"id":42,"path":"sub","full_path":"platform/sub"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		kind    string
+		owner   string
+		want    string
+		wantErr bool
+	}{
+		{kind: "gitlab", owner: "42", want: "platform/sub"},
+		{kind: "gitlab", owner: "platform/sub", want: "platform/sub"},
+		{kind: "gitlab", owner: "43", wantErr: true},
+		{kind: "github", owner: "acme", want: "acme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind+" "+tt.owner, func(t *testing.T) {
+			provider := &Provider{Kind: tt.kind, BaseUrl: server.URL, Client: server.Client()}
+			got, err := provider.OwnerPath(tt.owner)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OwnerPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("OwnerPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeUrl(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://github.com/acme/Foo.git", want: "github.com/acme/foo"},
+		{url: "git@github.com:acme/foo.git", want: "github.com/acme/foo"},
+		{url: "ssh://git@github.com:22/acme/foo", want: "github.com/acme/foo"},
+		{url: "https://gitlab.com/platform/sub/foo/", want: "gitlab.com/platform/sub/foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := NormalizeUrl(tt.url); got != tt.want {
+				t.Errorf("NormalizeUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,40 +1,61 @@
 package git
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"github.com/mitchellh/go-homedir"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
-	"gopkg.in/src-d/go-git.v4/plumbing/transport"
-	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
-	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
-	"io/ioutil"
-	"os/user"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type Repository struct {
-	Url            string
-	Labels         []string
-	Name           string
+	Url    string
+	Labels []string
+	Name   string
+	// Existing local clone, used in place instead of a mirror
+	Path           string
 	LocalPath      string
 	Authentication Authentication
+	IssueTrackers  []IssueTracker `mapstructure:"issue_trackers"`
 }
 
 type Authentication struct {
 	Type     string
 	AuthFile string `mapstructure:"auth_file"`
+	// Passphrase source of an encrypted ssh key : file:<path>, env:<VAR>, cmd:<command> or prompt
+	Passphrase string
+	// Access token source : file:<path>, env:<VAR>, cmd:<command>, git-credential or netrc
+	Token           string
+	Username        string
+	HostKeyChecking `mapstructure:",squash"`
 }
 
 func (r Repository) ListCommits(filter Filter) (commits []Commit, e error) {
 
+	issueMatchers, err := r.issueMatchers()
+	if err != nil {
+		return nil, err
+	}
+
 	gitRepo, err := git.PlainOpen(r.LocalPath)
 	if err != nil {
-		return nil, fmt.Errorf("%v\n", err)
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
 	}
 
 	ref, err := gitRepo.Head()
 	if err != nil {
-		return nil, fmt.Errorf("%v\n", err)
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
 	}
 
 	commitIter, err := gitRepo.Log(&git.LogOptions{
@@ -44,82 +65,244 @@ func (r Repository) ListCommits(filter Filter) (commits []Commit, e error) {
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("%v\n", err)
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
 	}
 
 	err = commitIter.ForEach(func(c *object.Commit) error {
-		if filter.Filter(c) {
-			commits = append(commits, *NewCommit(c, gitRepo, r.Name))
+		if !filter.Filter(c) {
+			return nil
+		}
+		commit := NewCommit(c, gitRepo, r.Name)
+		commit.Issues = extractIssues(c.Message, issueMatchers)
+		if filter.FilterIssues(commit.Issues) {
+			commits = append(commits, *commit)
 		}
 		return nil
 	})
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
 
 	return commits, nil
 }
 
-func (r Repository) SyncRepo() error {
-
-	fmt.Println("Syncing " + r.Name + "...")
+// FindCommit returns the commit of the local copy matching hash
+func (r Repository) FindCommit(hash string) (*Commit, error) {
+	gitRepo, err := git.PlainOpen(r.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+	c, err := gitRepo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, fmt.Errorf("%v : commit %v : %v", r.Name, hash, err)
+	}
+	commit := NewCommit(c, gitRepo, r.Name)
+	return commit, nil
+}
 
-	var auth transport.AuthMethod
+// IsLocal returns whether the repository is an existing local clone, set by path or a file:// url
+func (r Repository) IsLocal() bool {
+	return r.Path != "" || strings.HasPrefix(r.Url, "file://")
+}
 
-	switch r.Authentication.Type {
-	case "ssh":
-		if r.Authentication.AuthFile == "" {
-			currentUser, _ := user.Current()
-			auth, _ = ssh.NewPublicKeysFromFile("git", currentUser.HomeDir+"/.ssh/id_rsa", "")
-		} else {
-			auth, _ = ssh.NewPublicKeysFromFile("git", r.Authentication.AuthFile, "")
-		}
-		break
-	case "access_token":
-		accessToken, err := ioutil.ReadFile(r.Authentication.AuthFile)
+// ResolveLocalPath returns the path of the repository used to list commits :
+// the local clone itself, or its mirror in gitPath.
+func (r Repository) ResolveLocalPath(gitPath string) (string, error) {
+	switch {
+	case r.Path != "":
+		return homedir.Expand(r.Path)
+	case strings.HasPrefix(r.Url, "file://"):
+		u, err := url.Parse(r.Url)
 		if err != nil {
-			return fmt.Errorf("%v : auth file error: %v\n", r.Name, err)
-		}
-		auth = &http.BasicAuth{
-			Username: "anything",
-			Password: string(accessToken),
+			return "", err
 		}
-		break
+		return u.Path, nil
 	default:
-		auth = nil
-		break
+		return filepath.Join(gitPath, r.Name), nil
+	}
+}
+
+// SyncRepo clones or fetches the mirror of the repository, returning the number of fetched objects
+func (r Repository) SyncRepo() (fetched int, err error) {
+	return r.SyncRepoContext(context.Background(), nil)
+}
+
+// SyncRepoContext syncs the repository like SyncRepo, the clone or fetch being aborted once ctx is done.
+// Objects are fetched first, then the references of the mirror are updated while holding refsLock if not nil,
+// for readers holding it not to see references being updated.
+func (r Repository) SyncRepoContext(ctx context.Context, refsLock sync.Locker) (fetched int, err error) {
+
+	// Local clones are used in place
+	if r.IsLocal() {
+		if _, err := git.PlainOpen(r.LocalPath); err != nil {
+			return 0, fmt.Errorf("%v : %v : %v", r.Name, r.LocalPath, err)
+		}
+		return 0, nil
 	}
 
+	auth, err := r.AuthMethod()
+	if err != nil {
+		return 0, err
+	}
+
+	// The server reports the number of objects sent in its progress messages
+	var progress bytes.Buffer
+
 	// Cloning repository
-	repo, err := git.PlainClone(r.LocalPath, true, &git.CloneOptions{
+	repo, err := git.PlainCloneContext(ctx, r.LocalPath, true, &git.CloneOptions{
 		URL:        r.Url,
 		Auth:       auth,
 		NoCheckout: true,
+		Progress:   &progress,
 	})
 
 	switch err {
 	case git.ErrRepositoryAlreadyExists:
 		repo, err = git.PlainOpen(r.LocalPath)
 		if err != nil {
-			return fmt.Errorf("clone error: %v\n", err)
+			return 0, fmt.Errorf("%v : clone error: %v", r.Name, err)
 		}
 		break
 	case nil:
 		break
 	default:
-		return fmt.Errorf("clone error: %v\n", err)
+		return 0, fmt.Errorf("%v : clone error: %v", r.Name, err)
 	}
 
 	//Fetching all branches
 	remote, err := repo.Remote("origin")
 
 	if err != nil {
-		return fmt.Errorf("%v\n", err)
+		return 0, fmt.Errorf("%v : %v", r.Name, err)
 	}
 	fetchOptions := &git.FetchOptions{
-		RefSpecs: []config.RefSpec{"refs/*:refs/*"},
+		RefSpecs: []config.RefSpec{config.RefSpec("+refs/*:" + fetchedRefsPrefix + "*")},
 		Auth:     auth,
+		Progress: &progress,
+		// Tags are fetched by the refspec, instead of being followed straight into refs/tags
+		Tags: git.NoTags,
+	}
+	if err := remote.FetchContext(ctx, fetchOptions); err != nil && err != git.NoErrAlreadyUpToDate {
+		return 0, fmt.Errorf("%v : fetch error: %v", r.Name, err)
+	}
+
+	if refsLock != nil {
+		refsLock.Lock()
+	}
+	err = moveFetchedRefs(repo)
+	if refsLock != nil {
+		refsLock.Unlock()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%v : %v", r.Name, err)
+	}
+
+	if err := touch(r.syncMarker()); err != nil {
+		return 0, fmt.Errorf("%v : %v", r.Name, err)
+	}
+	return fetchedObjects(progress.String()), nil
+}
+
+// Fetched references are staged under this prefix, then moved in place once their objects are stored
+const fetchedRefsPrefix = "refs/git-follow-up/fetched/"
+
+// moveFetchedRefs moves the fetched references in place, mirroring the references of the remote
+func moveFetchedRefs(repo *git.Repository) error {
+	refs, err := repo.References()
+	if err != nil {
+		return err
 	}
-	if err := remote.Fetch(fetchOptions); err != nil && err != git.NoErrAlreadyUpToDate {
-		return fmt.Errorf("fetching %v : %v\n", r.Name, err)
+	var fetched []*plumbing.Reference
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if strings.HasPrefix(ref.Name().String(), fetchedRefsPrefix) {
+			fetched = append(fetched, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	for _, ref := range fetched {
+		name := plumbing.ReferenceName("refs/" + strings.TrimPrefix(ref.Name().String(), fetchedRefsPrefix))
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(name, ref.Hash())); err != nil {
+			return err
+		}
+		if err := repo.Storer.RemoveReference(ref.Name()); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+var totalObjects = regexp.MustCompile(`Total (\d+)`)
+
+// fetchedObjects sums the object totals reported in the progress messages of the server
+func fetchedObjects(progress string) (fetched int) {
+	for _, match := range totalObjects.FindAllStringSubmatch(progress, -1) {
+		total, _ := strconv.Atoi(match[1])
+		fetched += total
+	}
+	return fetched
+}
+
+// syncMarker is the file touched after each successful sync, inside the mirror
+// so that it can't be the mirror of another repository
+func (r Repository) syncMarker() string {
+	return filepath.Join(r.LocalPath, "git-follow-up-synced")
+}
+
+// legacySyncMarker is the file touched next to the mirror by previous versions
+func (r Repository) legacySyncMarker() string {
+	return filepath.Clean(r.LocalPath) + ".synced"
+}
+
+func touch(path string) error {
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); !os.IsNotExist(err) {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// LastSync returns the time of the last successful sync of the mirror, zero if it never synced
+// Local clones are used in place, and have no last sync
+func (r Repository) LastSync() (time.Time, error) {
+	if r.IsLocal() {
+		return time.Time{}, nil
+	}
+	info, err := os.Stat(r.syncMarker())
+	if os.IsNotExist(err) {
+		// Mirrors not synced since the marker moved, the mirror of another repository being a directory
+		legacy, legacyErr := os.Stat(r.legacySyncMarker())
+		if legacyErr == nil && legacy.Mode().IsRegular() {
+			return legacy.ModTime(), nil
+		}
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// CountCommits counts the commits reachable from any reference of the local copy
+func (r Repository) CountCommits() (count int, err error) {
+	gitRepo, err := git.PlainOpen(r.LocalPath)
+	if err != nil {
+		return 0, fmt.Errorf("%v : %v", r.Name, err)
+	}
+	commitIter, err := gitRepo.Log(&git.LogOptions{All: true})
+	if err != nil {
+		return 0, fmt.Errorf("%v : %v", r.Name, err)
+	}
+	err = commitIter.ForEach(func(c *object.Commit) error {
+		count++
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,211 @@
+package git
+
+import (
+	"context"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// initTestRepo creates a repository with a worktree in a temp dir, removed once the test ends
+func initTestRepo(t *testing.T) (string, *git.Repository) {
+	dir, err := ioutil.TempDir("", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, repo
+}
+
+// testCommit writes a file in the worktree of repo and commits it
+func testCommit(t *testing.T, repo *git.Repository, file string, content string, message string, when time.Time) plumbing.Hash {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(worktree.Filesystem.Root(), file)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add(file); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "Jean", Email: "jean@example.com", When: when},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// countingLocker counts the locks of the references
+type countingLocker struct {
+	sync.Mutex
+	locks int
+}
+
+func (l *countingLocker) Lock() {
+	l.Mutex.Lock()
+	l.locks++
+}
+
+func TestSyncRepoContext(t *testing.T) {
+	srcDir, src := initTestRepo(t)
+	first := testCommit(t, src, "README.md", "# repo\n", "Initial commit", time.Now())
+	if _, err := src.CreateTag("v1.0.0", first, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mirrors, err := ioutil.TempDir("", "mirrors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mirrors)
+	repo := Repository{Name: "repo", Url: srcDir, LocalPath: filepath.Join(mirrors, "repo")}
+
+	assertRefs := func(want map[string]plumbing.Hash) {
+		mirror, err := git.PlainOpen(repo.LocalPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs, err := mirror.References()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]plumbing.Hash)
+		_ = refs.ForEach(func(ref *plumbing.Reference) error {
+			if strings.HasPrefix(ref.Name().String(), fetchedRefsPrefix) {
+				t.Errorf("fetched reference %v left in the mirror", ref.Name())
+			}
+			got[ref.Name().String()] = ref.Hash()
+			return nil
+		})
+		for name, hash := range want {
+			if got[name] != hash {
+				t.Errorf("reference %v = %v, want %v", name, got[name], hash)
+			}
+		}
+	}
+
+	locker := &countingLocker{}
+	if _, err := repo.SyncRepoContext(context.Background(), locker); err != nil {
+		t.Fatalf("SyncRepoContext() error = %v", err)
+	}
+	assertRefs(map[string]plumbing.Hash{"refs/heads/master": first, "refs/tags/v1.0.0": first})
+
+	second := testCommit(t, src, "main.go", "package main\n", "Add main", time.Now())
+	if _, err := repo.SyncRepoContext(context.Background(), locker); err != nil {
+		t.Fatalf("SyncRepoContext() error = %v", err)
+	}
+	assertRefs(map[string]plumbing.Hash{"refs/heads/master": second, "refs/tags/v1.0.0": first})
+
+	if locker.locks != 2 {
+		t.Errorf("references locked %d times, want once per sync", locker.locks)
+	}
+	if last, err := repo.LastSync(); err != nil || last.IsZero() {
+		t.Errorf("LastSync() = %v, %v, want the time of the sync", last, err)
+	}
+}
+
+func TestFetchedObjects(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress string
+		want     int
+	}{
+		{name: "up to date", progress: "", want: 0},
+		{
+			name:     "clone",
+			progress: "Enumerating objects: 120, done.\rCounting objects: 100% (120/120), done.\nTotal 120 (delta 45), reused 98 (delta 30), pack-reused 0\n",
+			want:     120,
+		},
+		{
+			name:     "clone and fetch",
+			progress: "Total 120 (delta 45), reused 98 (delta 30)\nTotal 7 (delta 2), reused 0 (delta 0)\n",
+			want:     127,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fetchedObjects(tt.progress); got != tt.want {
+				t.Errorf("fetchedObjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lastsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	repo := Repository{Name: "repo", Url: "https://example.com/repo.git", LocalPath: filepath.Join(dir, "repo") + "/"}
+	if err := os.MkdirAll(repo.LocalPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	// The mirror of repo.synced, where the marker of repo used to be
+	synced := filepath.Join(dir, "repo.synced")
+	if err := os.MkdirAll(synced, 0700); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Date(2019, time.May, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(synced, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := repo.LastSync()
+	if err != nil || !last.IsZero() {
+		t.Fatalf("LastSync() = %v, %v, want zero time before any sync", last, err)
+	}
+
+	before := time.Now().Add(-time.Second)
+	if err := touch(repo.syncMarker()); err != nil {
+		t.Fatal(err)
+	}
+	if err := touch(repo.syncMarker()); err != nil {
+		t.Fatal(err)
+	}
+	last, err = repo.LastSync()
+	if err != nil || last.Before(before) {
+		t.Errorf("LastSync() = %v, %v, want after %v", last, err, before)
+	}
+	if info, err := os.Stat(synced); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("mirror of repo.synced touched by the sync of repo")
+	}
+
+	// Marker of a previous version, next to the mirror
+	legacy := Repository{Name: "legacy", Url: "https://example.com/legacy.git", LocalPath: filepath.Join(dir, "legacy")}
+	if err := os.MkdirAll(legacy.LocalPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := touch(legacy.legacySyncMarker()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(legacy.legacySyncMarker(), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := legacy.LastSync(); err != nil || !last.Equal(old) {
+		t.Errorf("LastSync() = %v, %v, want %v from the previous marker", last, err, old)
+	}
+
+	local := Repository{Name: "local", Path: dir, LocalPath: dir}
+	if last, err := local.LastSync(); err != nil || !last.IsZero() {
+		t.Errorf("LastSync() = %v, %v, want zero time for a local clone", last, err)
+	}
+}
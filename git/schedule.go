@@ -0,0 +1,83 @@
+package git
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"time"
+)
+
+// Schedule syncs the selected repositories at an interval (15m, 1h) or on a cron expression (*/5 * * * *)
+// A schedule without repos nor labels selects every repository
+type Schedule struct {
+	Repos    []string
+	Labels   []string
+	Interval string
+	Cron     string
+}
+
+// Validate checks that the schedule has either a valid interval or a valid cron expression
+func (s Schedule) Validate() error {
+	_, err := s.schedule()
+	return err
+}
+
+func (s Schedule) schedule() (cron.Schedule, error) {
+	switch {
+	case s.Interval != "" && s.Cron != "":
+		return nil, fmt.Errorf("interval and cron are mutually exclusive")
+	case s.Cron != "":
+		schedule, err := cron.ParseStandard(s.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q : %v", s.Cron, err)
+		}
+		return schedule, nil
+	case s.Interval != "":
+		interval, err := time.ParseDuration(s.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q : %v", s.Interval, err)
+		}
+		if interval < time.Minute {
+			return nil, fmt.Errorf("invalid interval %q : must be at least 1m", s.Interval)
+		}
+		return cron.Every(interval), nil
+	}
+	return nil, fmt.Errorf("interval or cron is required")
+}
+
+// Next returns the time of the first sync after t
+func (s Schedule) Next(t time.Time) (time.Time, error) {
+	schedule, err := s.schedule()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(t), nil
+}
+
+// Matches tells whether the repository is selected by name, or has all the labels of the schedule
+func (s Schedule) Matches(repo Repository) bool {
+	if len(s.Repos) == 0 && len(s.Labels) == 0 {
+		return true
+	}
+	if Contains(s.Repos, repo.Name) {
+		return true
+	}
+	return len(s.Labels) > 0 && ContainsAll(repo.Labels, s.Labels)
+}
+
+func (s Schedule) String() string {
+	var when string
+	if s.Cron != "" {
+		when = "cron " + s.Cron
+	} else {
+		when = "every " + s.Interval
+	}
+	switch {
+	case len(s.Repos) > 0 && len(s.Labels) > 0:
+		return fmt.Sprintf("repos %v and labels %v, %s", s.Repos, s.Labels, when)
+	case len(s.Repos) > 0:
+		return fmt.Sprintf("repos %v, %s", s.Repos, when)
+	case len(s.Labels) > 0:
+		return fmt.Sprintf("labels %v, %s", s.Labels, when)
+	}
+	return "all repos, " + when
+}
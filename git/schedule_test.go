@@ -0,0 +1,60 @@
+package git
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	now := time.Date(2019, 6, 14, 10, 2, 30, 0, time.UTC)
+	tests := []struct {
+		name     string
+		schedule Schedule
+		want     time.Time
+		wantErr  bool
+	}{
+		{name: "interval", schedule: Schedule{Interval: "15m"}, want: now.Add(15 * time.Minute)},
+		{name: "cron", schedule: Schedule{Cron: "*/5 * * * *"}, want: time.Date(2019, 6, 14, 10, 5, 0, 0, time.UTC)},
+		{name: "daily cron", schedule: Schedule{Cron: "0 17 * * 5"}, want: time.Date(2019, 6, 14, 17, 0, 0, 0, time.UTC)},
+		{name: "both", schedule: Schedule{Interval: "15m", Cron: "*/5 * * * *"}, wantErr: true},
+		{name: "none", schedule: Schedule{}, wantErr: true},
+		{name: "invalid interval", schedule: Schedule{Interval: "15"}, wantErr: true},
+		{name: "short interval", schedule: Schedule{Interval: "10s"}, wantErr: true},
+		{name: "invalid cron", schedule: Schedule{Cron: "* * *"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.schedule.Next(now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Next() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_Matches(t *testing.T) {
+	repo := Repository{Name: "cobra", Labels: []string{"go", "cli"}}
+	tests := []struct {
+		name     string
+		schedule Schedule
+		want     bool
+	}{
+		{name: "all", schedule: Schedule{}, want: true},
+		{name: "by name", schedule: Schedule{Repos: []string{"viper", "cobra"}}, want: true},
+		{name: "other name", schedule: Schedule{Repos: []string{"viper"}}, want: false},
+		{name: "by labels", schedule: Schedule{Labels: []string{"go", "cli"}}, want: true},
+		{name: "missing label", schedule: Schedule{Labels: []string{"go", "prod"}}, want: false},
+		{name: "name or labels", schedule: Schedule{Repos: []string{"viper"}, Labels: []string{"go"}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Matches(repo); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
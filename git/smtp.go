@@ -0,0 +1,67 @@
+package git
+
+import (
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP is the mail server sending notifications and digests
+type SMTP struct {
+	Host string
+	// 25 by default, STARTTLS being used when the server supports it
+	Port     int
+	Username string
+	// Password source : file:<path>, env:<VAR> or cmd:<command>
+	Password string
+	From     string
+}
+
+// Send sends a mail with a body of the given content type, text/plain or text/html
+func (s SMTP) Send(to []string, subject string, contentType string, body string) error {
+	if s.Host == "" {
+		return fmt.Errorf("smtp host is not configured")
+	}
+	if s.From == "" {
+		return fmt.Errorf("smtp sender is not configured")
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("mail has no recipient")
+	}
+	port := s.Port
+	if port == 0 {
+		port = 25
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		password, err := readSecret(s.Password)
+		if err != nil {
+			return fmt.Errorf("smtp password: %v", err)
+		}
+		auth = smtp.PlainAuth("", s.Username, password, s.Host)
+	}
+
+	addr := s.Host + ":" + strconv.Itoa(port)
+	if err := smtp.SendMail(addr, auth, s.From, to, []byte(mailMessage(s.From, to, subject, contentType, body))); err != nil {
+		return fmt.Errorf("smtp %v : %v", addr, err)
+	}
+	return nil
+}
+
+func mailMessage(from string, to []string, subject string, contentType string, body string) string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	fmt.Fprintf(&message, "Content-Transfer-Encoding: 8bit\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.Replace(strings.Replace(body, "\r\n", "\n", -1), "\n", "\r\n", -1))
+	return message.String()
+}
@@ -0,0 +1,143 @@
+package git
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpServer is a stand-in SMTP server, recording the received messages
+type smtpServer struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &smtpServer{listener: listener, messages: make(chan string, 10)}
+	go server.serve()
+	return server
+}
+
+func (s *smtpServer) SMTP() SMTP {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return SMTP{Host: addr.IP.String(), Port: addr.Port, From: "git-follow-up@example.com"}
+}
+
+func (s *smtpServer) Close() {
+	_ = s.listener.Close()
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost ready")
+	var envelope []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM"), strings.HasPrefix(command, "RCPT TO"):
+			envelope = append(envelope, strings.TrimSpace(line))
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.messages <- strings.Join(envelope, "\r\n") + "\r\n\r\n" + data.String()
+			envelope = nil
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTP_Send(t *testing.T) {
+	server := newSMTPServer(t)
+	defer server.Close()
+
+	err := server.SMTP().Send([]string{"oncall@example.com", "team@example.com"}, "Weekly digest", "text/html", "<p>Hello</p>\n")
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	message := <-server.messages
+	for _, want := range []string{
+		"RCPT TO:<oncall@example.com>",
+		"RCPT TO:<team@example.com>",
+		"From: git-follow-up@example.com\r\n",
+		"To: oncall@example.com, team@example.com\r\n",
+		"Subject: Weekly digest\r\n",
+		"Content-Type: text/html; charset=utf-8\r\n",
+		"\r\n\r\n<p>Hello</p>\r\n",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message doesn't contain %q :\n%v", want, message)
+		}
+	}
+}
+
+func TestSMTP_Send_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		smtp SMTP
+		to   []string
+	}{
+		{name: "no host", smtp: SMTP{From: "a@example.com"}, to: []string{"b@example.com"}},
+		{name: "no sender", smtp: SMTP{Host: "localhost"}, to: []string{"b@example.com"}},
+		{name: "no recipient", smtp: SMTP{Host: "localhost", From: "a@example.com"}},
+		{name: "unreachable", smtp: SMTP{Host: "127.0.0.1", Port: unusedPort(t), From: "a@example.com"}, to: []string{"b@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.smtp.Send(tt.to, "subject", "text/plain", "body"); err == nil {
+				t.Errorf("Send() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func unusedPort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port, _ := strconv.Atoi(strings.Split(listener.Addr().String(), ":")[1])
+	return port
+}
@@ -0,0 +1,48 @@
+package git
+
+import (
+	"sort"
+)
+
+// AuthorStat counts the commits authored and co-authored by a contributor
+type AuthorStat struct {
+	Name       string `json:"name"`
+	Commits    int    `json:"commits"`
+	CoAuthored int    `json:"co_authored"`
+}
+
+// AuthorStats counts commits by author name, crediting Co-authored-by trailers.
+// Stats are sorted by decreasing number of contributions.
+func AuthorStats(commits []Commit) (stats []AuthorStat) {
+	index := make(map[string]int)
+	credit := func(name string, coAuthored bool) {
+		i, ok := index[name]
+		if !ok {
+			i = len(stats)
+			index[name] = i
+			stats = append(stats, AuthorStat{Name: name})
+		}
+		if coAuthored {
+			stats[i].CoAuthored++
+		} else {
+			stats[i].Commits++
+		}
+	}
+
+	for _, c := range commits {
+		credit(c.Commit.Author.Name, false)
+		for _, coAuthor := range CoAuthors(c.Commit.Message) {
+			if name := IdentityName(coAuthor); name != c.Commit.Author.Name {
+				credit(name, true)
+			}
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Commits+stats[i].CoAuthored != stats[j].Commits+stats[j].CoAuthored {
+			return stats[i].Commits+stats[i].CoAuthored > stats[j].Commits+stats[j].CoAuthored
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
@@ -0,0 +1,72 @@
+package git
+
+import (
+	"fmt"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"time"
+)
+
+// Tag is a tag of a repository, dated by its tagger, or by its commit for lightweight tags
+type Tag struct {
+	Name       string
+	Repository string
+	Date       time.Time
+	Commit     *object.Commit
+	// Message of annotated tags
+	Message string
+}
+
+// ListTags lists the tags of the local copy pointing to commits
+func (r Repository) ListTags() (tags []Tag, e error) {
+	gitRepo, err := git.PlainOpen(r.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+	refs, err := gitRepo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		tag := Tag{Name: ref.Name().Short(), Repository: r.Name}
+		if annotated, err := gitRepo.TagObject(ref.Hash()); err == nil {
+			commit, err := annotated.Commit()
+			if err != nil {
+				// Tag of a tree or a blob
+				return nil
+			}
+			tag.Commit = commit
+			tag.Date = annotated.Tagger.When
+			tag.Message = annotated.Message
+		} else {
+			commit, err := gitRepo.CommitObject(ref.Hash())
+			if err != nil {
+				return nil
+			}
+			tag.Commit = commit
+			tag.Date = commit.Committer.When
+		}
+		tags = append(tags, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v : %v", r.Name, err)
+	}
+	return tags, nil
+}
+
+// FindTag returns the tag of the local copy named name
+func (r Repository) FindTag(name string) (*Tag, error) {
+	tags, err := r.ListTags()
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if tag.Name == name {
+			return &tag, nil
+		}
+	}
+	return nil, fmt.Errorf("%v : tag %v not found", r.Name, name)
+}
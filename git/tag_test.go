@@ -0,0 +1,70 @@
+package git
+
+import (
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"testing"
+	"time"
+)
+
+func TestFindTag(t *testing.T) {
+	dir, repo := initTestRepo(t)
+	first := testCommit(t, repo, "main.go", "package main\n", "Initial commit", time.Date(2019, time.May, 1, 12, 0, 0, 0, time.UTC))
+	second := testCommit(t, repo, "main.go", "package main\n\n// 1.1\n", "feat: release 1.1", time.Date(2019, time.May, 3, 12, 0, 0, 0, time.UTC))
+	if _, err := repo.CreateTag("v1.0", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1.1", second, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Jean", Email: "jean@example.com", When: time.Date(2019, time.May, 4, 12, 0, 0, 0, time.UTC)},
+		Message: "Release 1.1",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := Repository{Name: "app", LocalPath: dir}
+
+	tests := []struct {
+		name     string
+		tag      string
+		wantHash string
+		wantDate time.Time
+		wantErr  string
+	}{
+		{
+			name:     "lightweight",
+			tag:      "v1.0",
+			wantHash: first.String(),
+			wantDate: time.Date(2019, time.May, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "annotated",
+			tag:      "v1.1",
+			wantHash: second.String(),
+			wantDate: time.Date(2019, time.May, 4, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "unknown",
+			tag:     "v2.0",
+			wantErr: "app : tag v2.0 not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := r.FindTag(tt.tag)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("FindTag() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tag.Commit.Hash.String() != tt.wantHash {
+				t.Errorf("FindTag() commit = %v, want %v", tag.Commit.Hash, tt.wantHash)
+			}
+			if !tag.Date.Equal(tt.wantDate) {
+				t.Errorf("FindTag() date = %v, want %v", tag.Date, tt.wantDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package git
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Trailer is a "Key: value" line of the last paragraph of a commit message
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+var trailerRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):\s*(.*)$`)
+
+// ParseTrailers returns the trailers of a commit message, such as Co-authored-by or Signed-off-by.
+// The last paragraph of the message is considered as trailers only if every line is a trailer
+// or the continuation of a trailer value.
+func ParseTrailers(message string) (trailers []Trailer) {
+	paragraphs := strings.Split(strings.TrimSpace(strings.Replace(message, "\r\n", "\n", -1)), "\n\n")
+	// The subject line can't hold trailers
+	if len(paragraphs) < 2 {
+		return nil
+	}
+
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		if len(trailers) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			trailers[len(trailers)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		matches := trailerRegex.FindStringSubmatch(line)
+		if matches == nil {
+			return nil
+		}
+		trailers = append(trailers, Trailer{Key: matches[1], Value: strings.TrimSpace(matches[2])})
+	}
+	return trailers
+}
+
+// TrailerValues returns the values of the trailers matching key, case insensitively
+func TrailerValues(trailers []Trailer, key string) (values []string) {
+	for _, t := range trailers {
+		if strings.EqualFold(t.Key, key) {
+			values = append(values, t.Value)
+		}
+	}
+	return values
+}
+
+// CoAuthors returns the "Name <email>" identities of the Co-authored-by trailers of a commit message
+func CoAuthors(message string) []string {
+	return TrailerValues(ParseTrailers(message), "Co-authored-by")
+}
+
+// IdentityName returns the name part of a "Name <email>" identity
+func IdentityName(identity string) string {
+	if i := strings.Index(identity, "<"); i > 0 {
+		return strings.TrimSpace(identity[:i])
+	}
+	return strings.TrimSpace(identity)
+}
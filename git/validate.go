@@ -0,0 +1,66 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var nameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ValidateName checks that a repository name can safely be used as a directory name of the local copies
+func ValidateName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("name is required")
+	case name == "." || name == "..":
+		return fmt.Errorf("invalid name %q", name)
+	// Hidden directories of the mirrors are reserved, such as the lock directory
+	case strings.HasPrefix(name, "."):
+		return fmt.Errorf("invalid name %q : names starting with '.' are reserved", name)
+	case !nameRegex.MatchString(name):
+		return fmt.Errorf("invalid name %q : only letters, digits, '.', '_' and '-' are allowed", name)
+	}
+	return nil
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// SanitizeName turns a directory or project name into a valid repository name,
+// runs of characters not allowed being replaced by '-'. It returns "" if no valid name is left.
+func SanitizeName(name string) string {
+	if ValidateName(name) == nil {
+		return name
+	}
+	name = strings.TrimLeft(strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-"), ".")
+	if ValidateName(name) != nil {
+		return ""
+	}
+	return name
+}
+
+var UrlSchemes = []string{"https", "http", "ssh", "git", "file"}
+
+// ValidateUrl checks that a clone url is a supported url, or a scp-like ssh address (git@host:path)
+func ValidateUrl(rawUrl string) error {
+	if rawUrl == "" {
+		return fmt.Errorf("url is required")
+	}
+	if u, err := url.Parse(rawUrl); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		if !Contains(UrlSchemes, u.Scheme) {
+			return fmt.Errorf("invalid url %q : unsupported scheme %v", rawUrl, u.Scheme)
+		}
+		if u.Scheme != "file" && u.Host == "" {
+			return fmt.Errorf("invalid url %q : host is missing", rawUrl)
+		}
+		if u.Scheme == "file" && u.Path == "" {
+			return fmt.Errorf("invalid url %q : path is missing", rawUrl)
+		}
+		return nil
+	}
+	if matches := scpUrlRegex.FindStringSubmatch(rawUrl); matches != nil && matches[2] != "" {
+		return nil
+	}
+	return fmt.Errorf("invalid url %q", rawUrl)
+}
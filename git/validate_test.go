@@ -0,0 +1,74 @@
+package git
+
+import (
+	"testing"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "go-git", wantErr: false},
+		{name: "viper_1.2", wantErr: false},
+		{name: "", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "../etc", wantErr: true},
+		{name: ".locks", wantErr: true},
+		{name: "foo.lock", wantErr: false},
+		{name: "acme/foo", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateName(tt.name); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "go-git", want: "go-git"},
+		{name: "-draft-", want: "-draft-"},
+		{name: "my tools", want: "my-tools"},
+		{name: "acme/foo bar", want: "acme-foo-bar"},
+		{name: " (old) ", want: "old"},
+		{name: "..", want: ""},
+		{name: ".dotfiles", want: "dotfiles"},
+		{name: "日本", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeName(tt.name); got != tt.want {
+				t.Errorf("SanitizeName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateUrl(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://github.com/spf13/cobra.git", wantErr: false},
+		{url: "git@github.com:src-d/go-git.git", wantErr: false},
+		{url: "ssh://git@github.com:22/src-d/go-git.git", wantErr: false},
+		{url: "file:///home/me/src/foo", wantErr: false},
+		{url: "", wantErr: true},
+		{url: "ftp://example.com/foo", wantErr: true},
+		{url: "https:///foo", wantErr: true},
+		{url: "foo", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := ValidateUrl(tt.url); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUrl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package git
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Maximum size of a webhook payload, as sent by GitHub
+const maxWebhookPayload = 25 << 20
+
+// ErrWebhookUnauthorized is returned for webhooks without a valid signature or secret token
+var ErrWebhookUnauthorized = errors.New("invalid webhook signature or token")
+
+// Webhook validates the push webhooks of GitHub, GitLab and Gitea against a shared secret
+type Webhook struct {
+	Secret string
+}
+
+// PushEvent is a push to a repository, notified by a webhook
+type PushEvent struct {
+	Provider string
+	// Clone and web urls of the repository
+	Urls []string
+}
+
+// NewWebhook reads the secret from a source : file:<path>, env:<VAR> or cmd:<command>
+func NewWebhook(secretSource string) (*Webhook, error) {
+	secret, err := readSecret(secretSource)
+	if err != nil {
+		return nil, fmt.Errorf("webhook secret: %v", err)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("webhook secret is empty")
+	}
+	return &Webhook{Secret: secret}, nil
+}
+
+// Parse validates a webhook request and reads the pushed repository
+// Events other than pushes are valid but ignored, returning a nil event
+func (w Webhook) Parse(r *http.Request) (*PushEvent, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxWebhookPayload))
+	if err != nil {
+		return nil, err
+	}
+
+	// Gitea also sends the GitHub headers
+	switch {
+	case r.Header.Get("X-Gitea-Event") != "":
+		if !w.validSignature(r.Header.Get("X-Gitea-Signature"), body) {
+			return nil, ErrWebhookUnauthorized
+		}
+		if r.Header.Get("X-Gitea-Event") != "push" {
+			return nil, nil
+		}
+		return parseGithubPush("gitea", body)
+	case r.Header.Get("X-GitHub-Event") != "":
+		if !w.validSignature(strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="), body) {
+			return nil, ErrWebhookUnauthorized
+		}
+		if r.Header.Get("X-GitHub-Event") != "push" {
+			return nil, nil
+		}
+		return parseGithubPush("github", body)
+	case r.Header.Get("X-Gitlab-Event") != "":
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(w.Secret)) != 1 {
+			return nil, ErrWebhookUnauthorized
+		}
+		if event := r.Header.Get("X-Gitlab-Event"); event != "Push Hook" && event != "Tag Push Hook" {
+			return nil, nil
+		}
+		return parseGitlabPush(body)
+	}
+	return nil, fmt.Errorf("unknown webhook provider : X-GitHub-Event, X-Gitlab-Event or X-Gitea-Event header expected")
+}
+
+// validSignature checks the hex encoded HMAC-SHA256 of the payload
+func (w Webhook) validSignature(signature string, body []byte) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func parseGithubPush(provider string, body []byte) (*PushEvent, error) {
+	var payload struct {
+		Repository struct {
+			CloneUrl string `json:"clone_url"`
+			SshUrl   string `json:"ssh_url"`
+			GitUrl   string `json:"git_url"`
+			HtmlUrl  string `json:"html_url"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%v webhook payload: %v", provider, err)
+	}
+	repo := payload.Repository
+	return newPushEvent(provider, repo.CloneUrl, repo.SshUrl, repo.GitUrl, repo.HtmlUrl)
+}
+
+func parseGitlabPush(body []byte) (*PushEvent, error) {
+	var payload struct {
+		Project struct {
+			GitHttpUrl string `json:"git_http_url"`
+			GitSshUrl  string `json:"git_ssh_url"`
+			WebUrl     string `json:"web_url"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("gitlab webhook payload: %v", err)
+	}
+	project := payload.Project
+	return newPushEvent("gitlab", project.GitHttpUrl, project.GitSshUrl, project.WebUrl)
+}
+
+func newPushEvent(provider string, urls ...string) (*PushEvent, error) {
+	event := &PushEvent{Provider: provider}
+	for _, u := range urls {
+		if u != "" {
+			event.Urls = append(event.Urls, u)
+		}
+	}
+	if len(event.Urls) == 0 {
+		return nil, fmt.Errorf("%v webhook payload: repository url is missing", provider)
+	}
+	return event, nil
+}
+
+// Matches tells whether the repository is mirrored from the pushed repository, whatever the protocol of its url
+func (e PushEvent) Matches(repo Repository) bool {
+	if repo.IsLocal() || repo.Url == "" {
+		return false
+	}
+	normalized := NormalizeUrl(repo.Url)
+	for _, u := range e.Urls {
+		if NormalizeUrl(u) == normalized {
+			return true
+		}
+	}
+	return false
+}
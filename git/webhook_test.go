@@ -0,0 +1,121 @@
+package git
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sign(secret string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhook_Parse(t *testing.T) {
+	githubPayload := `{"ref":"refs/heads/master","repository":{"clone_url":"https://github.com/spf13/cobra.git","ssh_url":"git@github.com:spf13/cobra.git"}}`
+	gitlabPayload := `{"object_kind":"push","project":{"git_http_url":"https://gitlab.com/acme/api.git","git_ssh_url":"git@gitlab.com:acme/api.git"}}`
+	tests := []struct {
+		name    string
+		headers map[string]string
+		body    string
+		wantUrl string
+		wantErr error
+		wantNil bool
+	}{
+		{
+			name:    "github",
+			headers: map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + sign("secret", githubPayload)},
+			body:    githubPayload,
+			wantUrl: "https://github.com/spf13/cobra.git",
+		},
+		{
+			name:    "github invalid signature",
+			headers: map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + sign("other", githubPayload)},
+			body:    githubPayload,
+			wantErr: ErrWebhookUnauthorized,
+		},
+		{
+			name:    "github missing signature",
+			headers: map[string]string{"X-GitHub-Event": "push"},
+			body:    githubPayload,
+			wantErr: ErrWebhookUnauthorized,
+		},
+		{
+			name:    "github ping",
+			headers: map[string]string{"X-GitHub-Event": "ping", "X-Hub-Signature-256": "sha256=" + sign("secret", `{}`)},
+			body:    `{}`,
+			wantNil: true,
+		},
+		{
+			name:    "gitea",
+			headers: map[string]string{"X-Gitea-Event": "push", "X-GitHub-Event": "push", "X-Gitea-Signature": sign("secret", githubPayload)},
+			body:    githubPayload,
+			wantUrl: "https://github.com/spf13/cobra.git",
+		},
+		{
+			name:    "gitlab",
+			headers: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "secret"},
+			body:    gitlabPayload,
+			wantUrl: "https://gitlab.com/acme/api.git",
+		},
+		{
+			name:    "gitlab invalid token",
+			headers: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "other"},
+			body:    gitlabPayload,
+			wantErr: ErrWebhookUnauthorized,
+		},
+		{
+			name:    "gitlab merge request",
+			headers: map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Token": "secret"},
+			body:    `{}`,
+			wantNil: true,
+		},
+	}
+	webhook := Webhook{Secret: "secret"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/webhook", strings.NewReader(tt.body))
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			event, err := webhook.Parse(r)
+			if err != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if (event == nil) != tt.wantNil {
+				t.Fatalf("Parse() = %v, want nil %v", event, tt.wantNil)
+			}
+			if event != nil && event.Urls[0] != tt.wantUrl {
+				t.Errorf("Parse() urls = %v, want %v", event.Urls, tt.wantUrl)
+			}
+		})
+	}
+}
+
+func TestPushEvent_Matches(t *testing.T) {
+	event := PushEvent{Urls: []string{"https://github.com/spf13/cobra.git", "git@github.com:spf13/cobra.git"}}
+	tests := []struct {
+		name string
+		repo Repository
+		want bool
+	}{
+		{name: "https", repo: Repository{Url: "https://github.com/spf13/cobra"}, want: true},
+		{name: "ssh", repo: Repository{Url: "ssh://git@github.com/spf13/cobra.git"}, want: true},
+		{name: "other repo", repo: Repository{Url: "https://github.com/spf13/viper"}, want: false},
+		{name: "local clone", repo: Repository{Path: "~/src/cobra"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := event.Matches(tt.repo); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}